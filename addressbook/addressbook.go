@@ -0,0 +1,152 @@
+// Package addressbook 给常用地址起标签，命令行里可以用 @alice 或 token:MTK 代替十六进制地址，
+// 输出时也会在已知地址旁边标出名字。条目可以带标签(tag)，也可以限定只在某条链上生效。
+package addressbook
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"yunlabs.com/goethereumbook/ethaddr"
+)
+
+// Entry is a labeled address as stored in the config file.
+type Entry struct {
+	Label   string   `mapstructure:"label" yaml:"label" json:"label"`
+	Address string   `mapstructure:"address" yaml:"address" json:"address"`
+	Tags    []string `mapstructure:"tags" yaml:"tags,omitempty" json:"tags,omitempty"`
+	Chain   uint64   `mapstructure:"chain" yaml:"chain,omitempty" json:"chain,omitempty"` // 0 表示所有网络
+}
+
+// HasTag reports whether the entry carries tag.
+func (e Entry) HasTag(tag string) bool {
+	for _, t := range e.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+var ErrNotFound = errors.New("not in the address book")
+
+// Book is an address book seen from one chain: entries scoped to other
+// chains are kept but never resolved or used as labels.
+type Book struct {
+	entries []Entry
+	chain   uint64
+}
+
+// New creates a book for chain (0 if unknown, in which case only unscoped
+// entries are used).
+func New(entries []Entry, chain uint64) *Book {
+	return &Book{entries: entries, chain: chain}
+}
+
+// Entries returns all entries, including those scoped to other chains.
+func (b *Book) Entries() []Entry {
+	return b.entries
+}
+
+// Scoped returns the entries that apply to the book's chain.
+func (b *Book) Scoped() []Entry {
+	var entries []Entry
+	for _, e := range b.entries {
+		if b.inScope(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
+
+func (b *Book) inScope(e Entry) bool {
+	return e.Chain == 0 || e.Chain == b.chain
+}
+
+// IsName reports whether s is an address book reference such as @alice or
+// token:MTK rather than a hex address.
+func IsName(s string) bool {
+	if strings.HasPrefix(s, "@") {
+		return true
+	}
+	return !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") && strings.Contains(s, ":")
+}
+
+// Resolve looks up @label or tag:label. Entries scoped to the current chain
+// take precedence over unscoped ones.
+func (b *Book) Resolve(name string) (common.Address, error) {
+	var tag, label string
+	if strings.HasPrefix(name, "@") {
+		label = name[1:]
+	} else if i := strings.IndexByte(name, ':'); i >= 0 {
+		tag, label = name[:i], name[i+1:]
+	} else {
+		label = name
+	}
+
+	var found *Entry
+	for i, e := range b.entries {
+		if !b.inScope(e) || !strings.EqualFold(e.Label, label) || (tag != "" && !e.HasTag(tag)) {
+			continue
+		}
+		if found == nil || e.Chain != 0 {
+			found = &b.entries[i]
+		}
+	}
+	if found == nil {
+		return common.Address{}, fmt.Errorf("%s: %w", name, ErrNotFound)
+	}
+	return ethaddr.Parse(found.Address)
+}
+
+// Label returns the label of addr, or "" if it is unknown.
+func (b *Book) Label(addr common.Address) string {
+	label := ""
+	for _, e := range b.entries {
+		if !b.inScope(e) || !strings.EqualFold(e.Address, addr.Hex()) {
+			continue
+		}
+		if label == "" || e.Chain != 0 {
+			label = e.Label
+		}
+	}
+	return label
+}
+
+// Add validates e and adds it, replacing an entry with the same label and
+// chain scope.
+func (b *Book) Add(e Entry) error {
+	if e.Label == "" || strings.ContainsAny(e.Label, "@:, \t") {
+		return fmt.Errorf("invalid label %q", e.Label)
+	}
+	addr, err := ethaddr.Parse(e.Address)
+	if err != nil {
+		return err
+	}
+	e.Address = addr.Hex()
+	for i, old := range b.entries {
+		if strings.EqualFold(old.Label, e.Label) && old.Chain == e.Chain {
+			b.entries[i] = e
+			return nil
+		}
+	}
+	b.entries = append(b.entries, e)
+	sort.SliceStable(b.entries, func(i, j int) bool {
+		return strings.ToLower(b.entries[i].Label) < strings.ToLower(b.entries[j].Label)
+	})
+	return nil
+}
+
+// Remove deletes the entry with label in the given chain scope.
+func (b *Book) Remove(label string, chain uint64) bool {
+	for i, e := range b.entries {
+		if strings.EqualFold(e.Label, label) && e.Chain == chain {
+			b.entries = append(b.entries[:i], b.entries[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package addressbook
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReadCSV reads entries from CSV rows of label,address[,tags[,chain]].
+// Tags are separated by ";". A header row starting with "label" is skipped.
+func ReadCSV(r io.Reader) ([]Entry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	cr.Comment = '#'
+
+	var entries []Entry
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		if line == 1 && strings.EqualFold(record[0], "label") {
+			continue
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("line %d: want label,address[,tags[,chain]]", line)
+		}
+		e := Entry{Label: record[0], Address: record[1]}
+		if len(record) > 2 && record[2] != "" {
+			for _, tag := range strings.Split(record[2], ";") {
+				if tag = strings.TrimSpace(tag); tag != "" {
+					e.Tags = append(e.Tags, tag)
+				}
+			}
+		}
+		if len(record) > 3 && record[3] != "" {
+			e.Chain, err = strconv.ParseUint(record[3], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid chain %q", line, record[3])
+			}
+		}
+		entries = append(entries, e)
+	}
+}
@@ -0,0 +1,166 @@
+// Package balances 在指定区块对一批地址拍余额快照(ETH 和若干 ERC20)，并比较两个快照的差异，
+// 用于月末对账。ETH 余额走 JSON-RPC 批量 eth_getBalance，代币余额走 Multicall，两者并发获取。
+package balances
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"yunlabs.com/goethereumbook/batch"
+	"yunlabs.com/goethereumbook/contracts/token"
+)
+
+// Token is an ERC20 token in a snapshot.
+type Token struct {
+	Address  common.Address `json:"address"`
+	Symbol   string         `json:"symbol"`
+	Decimals uint8          `json:"decimals"`
+}
+
+// Row holds the balances of one address. Tokens is keyed by token address.
+type Row struct {
+	Address common.Address              `json:"address"`
+	Label   string                      `json:"label,omitempty"`
+	ETH     *big.Int                    `json:"eth"`
+	Tokens  map[common.Address]*big.Int `json:"tokens,omitempty"`
+}
+
+// Snapshot is the balances of many addresses at one block.
+type Snapshot struct {
+	Block     uint64      `json:"block"`
+	BlockHash common.Hash `json:"blockHash"`
+	Time      uint64      `json:"timestamp"`
+	Tokens    []Token     `json:"tokens,omitempty"`
+	Rows      []Row       `json:"balances"`
+}
+
+// Take reads the ETH and token balances of addrs at header.
+func Take(ctx context.Context, c *rpc.Client, bc *batch.Client, header *types.Header, addrs []common.Address, tokens []common.Address) (*Snapshot, error) {
+	snap := &Snapshot{
+		Block:     header.Number.Uint64(),
+		BlockHash: header.Hash(),
+		Time:      header.Time,
+		Rows:      make([]Row, len(addrs)),
+	}
+	for i, addr := range addrs {
+		snap.Rows[i] = Row{Address: addr, Tokens: make(map[common.Address]*big.Int)}
+	}
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: header.Number}
+
+	var (
+		wg   sync.WaitGroup
+		errs = make([]error, 2)
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		errs[0] = ethBalances(ctx, c, header.Number, snap.Rows)
+	}()
+	go func() {
+		defer wg.Done()
+		snap.Tokens, errs[1] = tokenBalances(opts, bc, tokens, snap.Rows)
+	}()
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return snap, nil
+}
+
+// ethBalances 每 500 个地址一个批量请求
+func ethBalances(ctx context.Context, c *rpc.Client, number *big.Int, rows []Row) error {
+	const chunk = 500
+	for start := 0; start < len(rows); start += chunk {
+		end := start + chunk
+		if end > len(rows) {
+			end = len(rows)
+		}
+		elems := make([]rpc.BatchElem, end-start)
+		results := make([]hexutil.Big, end-start)
+		for i := range elems {
+			elems[i] = rpc.BatchElem{
+				Method: "eth_getBalance",
+				Args:   []interface{}{rows[start+i].Address, hexutil.EncodeBig(number)},
+				Result: &results[i],
+			}
+		}
+		if err := c.BatchCallContext(ctx, elems); err != nil {
+			return err
+		}
+		for i, elem := range elems {
+			if elem.Error != nil {
+				return fmt.Errorf("balance of %s: %v", rows[start+i].Address.Hex(), elem.Error)
+			}
+			rows[start+i].ETH = results[i].ToInt()
+		}
+	}
+	return nil
+}
+
+func tokenBalances(opts *bind.CallOpts, bc *batch.Client, tokens []common.Address, rows []Row) ([]Token, error) {
+	if len(tokens) == 0 {
+		return nil, nil
+	}
+	tokenABI, err := token.TokenMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+
+	// 代币信息和所有余额放进同一批调用
+	var calls []*batch.Call
+	for _, t := range tokens {
+		calls = append(calls, batch.NewCall(t, tokenABI, "symbol"), batch.NewCall(t, tokenABI, "decimals"))
+	}
+	meta := len(calls)
+	for _, t := range tokens {
+		for _, row := range rows {
+			calls = append(calls, batch.NewCall(t, tokenABI, "balanceOf", row.Address))
+		}
+	}
+	if err := bc.Do(opts, calls); err != nil {
+		return nil, err
+	}
+
+	infos := make([]Token, len(tokens))
+	for i, t := range tokens {
+		symbol, decimals := calls[2*i], calls[2*i+1]
+		if symbol.Err != nil || decimals.Err != nil {
+			return nil, fmt.Errorf("token %s: not an ERC20 at block %s", t.Hex(), opts.BlockNumber)
+		}
+		infos[i] = Token{Address: t, Symbol: symbol.Out[0].(string), Decimals: decimals.Out[0].(uint8)}
+	}
+	for i, call := range calls[meta:] {
+		t, row := tokens[i/len(rows)], &rows[i%len(rows)]
+		bal, err := call.Big()
+		if err != nil {
+			return nil, fmt.Errorf("%s balanceOf %s: %v", t.Hex(), row.Address.Hex(), err)
+		}
+		row.Tokens[t] = bal
+	}
+	return infos, nil
+}
+
+// Load reads a snapshot saved as JSON.
+func Load(file string) (*Snapshot, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	snap := new(Snapshot)
+	if err := json.Unmarshal(data, snap); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return snap, nil
+}
@@ -0,0 +1,80 @@
+package balances
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Change is the difference of one asset of one address between two
+// snapshots. Token is the zero address for ETH. Amounts are in the smallest
+// unit of the asset, Decimals tells how to display them.
+type Change struct {
+	Address  common.Address `json:"address"`
+	Label    string         `json:"label,omitempty"`
+	Asset    string         `json:"asset"`
+	Token    common.Address `json:"token"`
+	Decimals uint8          `json:"decimals"`
+	Before   *big.Int       `json:"before"`
+	After    *big.Int       `json:"after"`
+	Delta    *big.Int       `json:"delta"`
+}
+
+// Diff compares two snapshots. Addresses or tokens present in only one of
+// them count as zero in the other. Unchanged balances are left out.
+func Diff(a, b *Snapshot) []Change {
+	infos := make(map[common.Address]Token)
+	var tokens []common.Address
+	for _, list := range [][]Token{a.Tokens, b.Tokens} {
+		for _, t := range list {
+			if _, ok := infos[t.Address]; !ok {
+				infos[t.Address] = t
+				tokens = append(tokens, t.Address)
+			}
+		}
+	}
+
+	before := make(map[common.Address]Row)
+	for _, row := range a.Rows {
+		before[row.Address] = row
+	}
+	after := make(map[common.Address]Row)
+	var addrs []common.Address
+	for _, row := range a.Rows {
+		addrs = append(addrs, row.Address)
+	}
+	for _, row := range b.Rows {
+		after[row.Address] = row
+		if _, ok := before[row.Address]; !ok {
+			addrs = append(addrs, row.Address)
+		}
+	}
+
+	var changes []Change
+	add := func(addr common.Address, label string, t Token, x, y *big.Int) {
+		x, y = orZero(x), orZero(y)
+		if x.Cmp(y) == 0 {
+			return
+		}
+		changes = append(changes, Change{Address: addr, Label: label, Asset: t.Symbol, Token: t.Address, Decimals: t.Decimals, Before: x, After: y, Delta: new(big.Int).Sub(y, x)})
+	}
+	for _, addr := range addrs {
+		x, y := before[addr], after[addr]
+		label := y.Label
+		if label == "" {
+			label = x.Label
+		}
+		add(addr, label, Token{Symbol: "ETH", Decimals: 18}, x.ETH, y.ETH)
+		for _, t := range tokens {
+			add(addr, label, infos[t], x.Tokens[t], y.Tokens[t])
+		}
+	}
+	return changes
+}
+
+func orZero(n *big.Int) *big.Int {
+	if n == nil {
+		return new(big.Int)
+	}
+	return n
+}
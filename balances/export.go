@@ -0,0 +1,38 @@
+package balances
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// WriteCSV writes one row per address with the ETH balance in wei and token
+// balances in their smallest unit.
+func WriteCSV(w io.Writer, snap *Snapshot) error {
+	cw := csv.NewWriter(w)
+	header := []string{"block", "address", "label", "ETH"}
+	for _, t := range snap.Tokens {
+		header = append(header, t.Symbol)
+	}
+	cw.Write(header)
+	for _, row := range snap.Rows {
+		record := []string{strconv.FormatUint(snap.Block, 10), row.Address.Hex(), row.Label, orZero(row.ETH).String()}
+		for _, t := range snap.Tokens {
+			record = append(record, orZero(row.Tokens[t.Address]).String())
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteDiffCSV writes the changes between two snapshots.
+func WriteDiffCSV(w io.Writer, changes []Change) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"address", "label", "asset", "token", "before", "after", "delta"})
+	for _, c := range changes {
+		cw.Write([]string{c.Address.Hex(), c.Label, c.Asset, c.Token.Hex(), c.Before.String(), c.After.String(), c.Delta.String()})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,254 @@
+// Package batch 把大量只读合约调用合并成尽量少的请求：链上有 Multicall3 时打包成一次 aggregate3 调用，
+// 没有部署时退回到 JSON-RPC 批量请求(rpc.Client.BatchCallContext)，一个 HTTP 请求里带多个 eth_call。
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"yunlabs.com/goethereumbook/contracts/multicall"
+)
+
+// Multicall3Address is where Multicall3 is deployed on most chains.
+var Multicall3Address = common.HexToAddress("0xcA11bde05977b3631167028862bE2a173976CA11")
+
+// ErrReverted is set on calls that reverted.
+var ErrReverted = errors.New("call reverted")
+
+// Call is a single read. After Do, Out holds the unpacked return values or
+// Err tells why the call failed; other calls in the batch are unaffected.
+type Call struct {
+	Target common.Address
+	ABI    *abi.ABI
+	Method string
+	Args   []interface{}
+
+	Out []interface{}
+	Err error
+}
+
+// NewCall creates a call of method on target.
+func NewCall(target common.Address, contractABI *abi.ABI, method string, args ...interface{}) *Call {
+	return &Call{Target: target, ABI: contractABI, Method: method, Args: args}
+}
+
+// Client batches calls.
+type Client struct {
+	rpc    *rpc.Client
+	client *ethclient.Client
+
+	Multicall common.Address
+	BatchSize int // 每个 aggregate3 或 JSON-RPC 批量请求最多包含的调用数
+
+	mu       sync.Mutex
+	deployed map[string]bool // 按区块缓存 Multicall3 是否存在
+}
+
+// New creates a client using the Multicall3 contract at address.
+func New(c *rpc.Client, address common.Address) *Client {
+	return &Client{
+		rpc:       c,
+		client:    ethclient.NewClient(c),
+		Multicall: address,
+		BatchSize: 500,
+		deployed:  make(map[string]bool),
+	}
+}
+
+// Do runs calls at the block and sender given by opts (nil for latest).
+// The error is only for failures of the whole batch, per-call failures are
+// reported in Call.Err.
+func (c *Client) Do(opts *bind.CallOpts, calls []*Call) error {
+	if opts == nil {
+		opts = new(bind.CallOpts)
+	}
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	data := make([][]byte, len(calls))
+	for i, call := range calls {
+		input, err := call.ABI.Pack(call.Method, call.Args...)
+		if err != nil {
+			return fmt.Errorf("%s: %v", call.Method, err)
+		}
+		data[i] = input
+	}
+
+	useMulticall, err := c.hasMulticall(ctx, opts)
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(calls); start += c.batchSize() {
+		end := start + c.batchSize()
+		if end > len(calls) {
+			end = len(calls)
+		}
+		if useMulticall {
+			err = c.aggregate(ctx, opts, calls[start:end], data[start:end])
+		} else {
+			err = c.batchCall(ctx, opts, calls[start:end], data[start:end])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UsesMulticall reports whether calls at opts go through Multicall3.
+func (c *Client) UsesMulticall(opts *bind.CallOpts) (bool, error) {
+	if opts == nil {
+		opts = new(bind.CallOpts)
+	}
+	ctx := opts.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return c.hasMulticall(ctx, opts)
+}
+
+func (c *Client) batchSize() int {
+	if c.BatchSize <= 0 {
+		return 500
+	}
+	return c.BatchSize
+}
+
+func (c *Client) hasMulticall(ctx context.Context, opts *bind.CallOpts) (bool, error) {
+	key := blockArg(opts)
+	c.mu.Lock()
+	deployed, ok := c.deployed[key]
+	c.mu.Unlock()
+	if ok {
+		return deployed, nil
+	}
+
+	var code hexutil.Bytes
+	if err := c.rpc.CallContext(ctx, &code, "eth_getCode", c.Multicall, key); err != nil {
+		return false, err
+	}
+	deployed = len(code) > 0
+	if opts.BlockNumber != nil {
+		// latest/pending 可能随时变化，只缓存指定高度的结果
+		c.mu.Lock()
+		c.deployed[key] = deployed
+		c.mu.Unlock()
+	}
+	return deployed, nil
+}
+
+// aggregate 用 aggregate3 执行一批调用，allowFailure 为 true，单个调用回滚不影响其他调用
+func (c *Client) aggregate(ctx context.Context, opts *bind.CallOpts, calls []*Call, data [][]byte) error {
+	mcABI, err := multicall.Multicall3MetaData.GetAbi()
+	if err != nil {
+		return err
+	}
+	in := make([]multicall.Multicall3Call3, len(calls))
+	for i, call := range calls {
+		in[i] = multicall.Multicall3Call3{Target: call.Target, AllowFailure: true, CallData: data[i]}
+	}
+	input, err := mcABI.Pack("aggregate3", in)
+	if err != nil {
+		return err
+	}
+	msg := ethereum.CallMsg{From: opts.From, To: &c.Multicall, Data: input}
+	var output []byte
+	if opts.Pending {
+		output, err = c.client.PendingCallContract(ctx, msg)
+	} else {
+		output, err = c.client.CallContract(ctx, msg, opts.BlockNumber)
+	}
+	if err != nil {
+		return err
+	}
+	values, err := mcABI.Unpack("aggregate3", output)
+	if err != nil {
+		return err
+	}
+	results := *abi.ConvertType(values[0], new([]multicall.Multicall3Result)).(*[]multicall.Multicall3Result)
+	if len(results) != len(calls) {
+		return fmt.Errorf("multicall returned %d results for %d calls", len(results), len(calls))
+	}
+	for i, call := range calls {
+		if !results[i].Success {
+			call.Err = ErrReverted
+			continue
+		}
+		call.unpack(results[i].ReturnData)
+	}
+	return nil
+}
+
+// batchCall 没有 Multicall3 时，把每个调用作为一个 eth_call 放进同一个 JSON-RPC 批量请求
+func (c *Client) batchCall(ctx context.Context, opts *bind.CallOpts, calls []*Call, data [][]byte) error {
+	elems := make([]rpc.BatchElem, len(calls))
+	results := make([]hexutil.Bytes, len(calls))
+	for i, call := range calls {
+		arg := map[string]interface{}{
+			"to":   call.Target,
+			"data": hexutil.Bytes(data[i]),
+		}
+		if opts.From != (common.Address{}) {
+			arg["from"] = opts.From
+		}
+		elems[i] = rpc.BatchElem{
+			Method: "eth_call",
+			Args:   []interface{}{arg, blockArg(opts)},
+			Result: &results[i],
+		}
+	}
+	if err := c.rpc.BatchCallContext(ctx, elems); err != nil {
+		return err
+	}
+	for i, call := range calls {
+		if elems[i].Error != nil {
+			call.Err = elems[i].Error
+			continue
+		}
+		call.unpack(results[i])
+	}
+	return nil
+}
+
+func (call *Call) unpack(output []byte) {
+	if len(output) == 0 && len(call.ABI.Methods[call.Method].Outputs) > 0 {
+		call.Err = bind.ErrNoCode // 目标地址没有代码时调用会成功但没有返回值
+		return
+	}
+	call.Out, call.Err = call.ABI.Unpack(call.Method, output)
+}
+
+func blockArg(opts *bind.CallOpts) string {
+	if opts.Pending {
+		return "pending"
+	}
+	if opts.BlockNumber == nil {
+		return "latest"
+	}
+	return hexutil.EncodeBig(opts.BlockNumber)
+}
+
+// Big returns the first output as a *big.Int, for calls like balanceOf.
+func (call *Call) Big() (*big.Int, error) {
+	if call.Err != nil {
+		return nil, call.Err
+	}
+	v, ok := call.Out[0].(*big.Int)
+	if !ok {
+		return nil, fmt.Errorf("%s returned %T", call.Method, call.Out[0])
+	}
+	return v, nil
+}
@@ -0,0 +1,153 @@
+// Package blocks 把命令行中的区块参数解析成具体的区块：区块号、标签(latest、safe、finalized、earliest)
+// 或时间(RFC3339、日期、unix 秒)，时间通过二分查找找到该时刻或之后的第一个区块。
+// 查找过程中取到的区块头会缓存下来，之后的查找可以直接缩小范围。
+package blocks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrFuture is returned for a time after the latest block.
+var ErrFuture = errors.New("no block at or after that time yet")
+
+// HeaderReader is the part of ethclient.Client the finder needs.
+type HeaderReader interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+}
+
+// Finder resolves block arguments to headers and caches the headers it
+// fetches by number.
+type Finder struct {
+	client HeaderReader
+
+	mu      sync.Mutex
+	headers map[uint64]*types.Header
+}
+
+// NewFinder creates a finder reading headers from client.
+func NewFinder(client HeaderReader) *Finder {
+	return &Finder{client: client, headers: make(map[uint64]*types.Header)}
+}
+
+// HeaderByNumber returns the header at number, from the cache if possible.
+func (f *Finder) HeaderByNumber(ctx context.Context, number uint64) (*types.Header, error) {
+	f.mu.Lock()
+	h, ok := f.headers[number]
+	f.mu.Unlock()
+	if ok {
+		return h, nil
+	}
+	h, err := f.client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return nil, err
+	}
+	f.remember(h)
+	return h, nil
+}
+
+// remember 缓存按高度取到的区块头
+func (f *Finder) remember(h *types.Header) {
+	f.mu.Lock()
+	f.headers[h.Number.Uint64()] = h
+	f.mu.Unlock()
+}
+
+// bounds 用缓存的区块头把查找范围收窄到 [lo, hi]
+func (f *Finder) bounds(target uint64, lo, hi uint64) (uint64, uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for n, h := range f.headers {
+		if n < lo || n > hi {
+			continue
+		}
+		if h.Time >= target {
+			hi = n
+		} else {
+			lo = n + 1
+		}
+	}
+	return lo, hi
+}
+
+var tags = map[string]rpc.BlockNumber{
+	"latest":    rpc.LatestBlockNumber,
+	"safe":      rpc.SafeBlockNumber,
+	"finalized": rpc.FinalizedBlockNumber,
+	"earliest":  rpc.EarliestBlockNumber,
+}
+
+// Resolve parses s as a block number, a tag or a time and returns the
+// header. The empty string means latest.
+func (f *Finder) Resolve(ctx context.Context, s string) (*types.Header, error) {
+	if s == "" {
+		s = "latest"
+	}
+	if tag, ok := tags[strings.ToLower(s)]; ok {
+		return f.client.HeaderByNumber(ctx, big.NewInt(int64(tag)))
+	}
+	if n, err := strconv.ParseUint(s, 0, 64); err == nil {
+		return f.HeaderByNumber(ctx, n)
+	}
+	t, err := ParseTime(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid block %q: want a number, latest, safe, finalized, earliest or a time", s)
+	}
+	return f.ByTime(ctx, t)
+}
+
+// ParseTime accepts RFC3339 ("2024-01-01T00:00:00Z"), a UTC date
+// ("2024-01-01") or unix seconds prefixed with @ ("@1704067200").
+func ParseTime(s string) (time.Time, error) {
+	if strings.HasPrefix(s, "@") {
+		sec, err := strconv.ParseInt(s[1:], 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(sec, 0).UTC(), nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", s)
+}
+
+// ByTime returns the first block whose timestamp is at or after t.
+func (f *Finder) ByTime(ctx context.Context, t time.Time) (*types.Header, error) {
+	target := uint64(t.Unix())
+	latest, err := f.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	if latest.Time < target {
+		return nil, fmt.Errorf("%s: %w (latest block %d is at %s)", t.UTC().Format(time.RFC3339), ErrFuture, latest.Number, time.Unix(int64(latest.Time), 0).UTC().Format(time.RFC3339))
+	}
+
+	// 区块时间随高度单调递增，在 [0, latest] 中二分查找第一个 Time >= target 的区块
+	lo, hi := f.bounds(target, 0, latest.Number.Uint64())
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		h, err := f.HeaderByNumber(ctx, mid)
+		if err != nil {
+			return nil, err
+		}
+		if h.Time >= target {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	if lo == latest.Number.Uint64() {
+		return latest, nil
+	}
+	return f.HeaderByNumber(ctx, lo)
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/spf13/cobra"
+
+	"yunlabs.com/goethereumbook/vanity"
+)
+
+var vanityPattern vanity.Pattern
+var vanityWorkers int
+var vanityKeystoreDir string
+var vanityPassword string
+
+// Accounts
+var accountsCmd = &cobra.Command{
+	Use:     "accounts",
+	Aliases: []string{"account"},
+	Short:   "Account utilities: 账户工具",
+}
+
+var accountsVanityCmd = &cobra.Command{
+	Use:   "vanity",
+	Short: "search for a key whose address (or nonce 0 contract address) matches a pattern",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := vanityPattern.Validate(); err != nil {
+			log.Fatal(err)
+		}
+		if vanityPassword == "" {
+			log.Fatal("--password is required to encrypt the keystore")
+		}
+		difficulty := vanityPattern.Difficulty()
+		fmt.Printf("difficulty: %.0f keys, %d workers\n", difficulty, vanityWorkers)
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		progress := func(s vanity.Stats) {
+			// 搜索没有记忆，不论已经试了多少次，剩余的期望时间都是 difficulty / rate
+			rate := s.Rate()
+			eta := "unknown"
+			if rate > 0 {
+				eta = time.Duration(difficulty / rate * float64(time.Second)).Round(time.Second).String()
+			}
+			fmt.Fprintf(os.Stderr, "tried %d, %.0f keys/s, expected %s\n", s.Tried, rate, eta)
+		}
+		start := time.Now()
+		res, err := vanity.Search(ctx, vanityPattern, vanityWorkers, 2*time.Second, progress)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("found in", time.Since(start).Round(time.Millisecond))
+		fmt.Println("address:", res.Address.Hex())
+		if vanityPattern.Contract {
+			fmt.Println("contract (nonce 0):", res.Contract.Hex())
+		}
+
+		// 私钥不打印出来，直接加密写入 keystore
+		ks := keystore.NewKeyStore(vanityKeystoreDir, keystore.StandardScryptN, keystore.StandardScryptP)
+		account, err := ks.ImportECDSA(res.Key, vanityPassword)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("keystore:", account.URL.Path)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(accountsCmd)
+	accountsCmd.AddCommand(accountsVanityCmd)
+
+	flags := accountsVanityCmd.Flags()
+	flags.StringVar(&vanityPattern.Prefix, "prefix", "", "hex prefix after 0x")
+	flags.StringVar(&vanityPattern.Suffix, "suffix", "", "hex suffix")
+	flags.StringVar(&vanityPattern.Contains, "contains", "", "hex string anywhere in the address")
+	flags.BoolVar(&vanityPattern.CaseSensitive, "case-sensitive", false, "letters must match the EIP-55 checksum casing")
+	flags.BoolVar(&vanityPattern.Contract, "contract", false, "match the address of the contract deployed at nonce 0")
+	flags.IntVar(&vanityWorkers, "workers", runtime.NumCPU(), "number of goroutines")
+	flags.StringVar(&vanityKeystoreDir, "keystore-dir", "./wallets", "keystore directory to write the key to")
+	flags.StringVar(&vanityPassword, "password", "", "keystore password")
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+
+	"yunlabs.com/goethereumbook/addressbook"
+	"yunlabs.com/goethereumbook/deployer"
+	"yunlabs.com/goethereumbook/ethaddr"
+)
+
+// Address
+var addressCmd = &cobra.Command{
+	Use:   "address",
+	Short: "Address utilities: 地址校验和合约地址预测",
+}
+
+var addressCheckCmd = &cobra.Command{
+	Use:   "check <address>...",
+	Short: "check format, EIP-55 checksum and whether an address is a contract",
+	Args:  cobra.MinimumNArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		client := dialClient()
+
+		for _, arg := range args {
+			fmt.Println(arg)
+			input, err := resolveAddressName(arg)
+			if err != nil {
+				fmt.Println("  valid: false,", err)
+				continue
+			}
+			address, checksum, err := ethaddr.Check(input)
+			if err != nil {
+				fmt.Println("  valid: false,", err)
+				continue
+			}
+			fmt.Println("  valid:", checksum != ethaddr.ChecksumInvalid)
+			fmt.Println("  checksum:", checksum)
+			fmt.Println("  normalized:", address.Hex())
+			if label := addressBook().Label(address); label != "" {
+				fmt.Println("  label:", label)
+			}
+
+			// 地址上有字节码就是合约，否则是普通账户(EOA)
+			bytecode, err := client.CodeAt(context.Background(), address, nil)
+			if err != nil {
+				log.Fatal(err)
+			}
+			if len(bytecode) > 0 {
+				fmt.Println("  type: contract")
+			} else {
+				fmt.Println("  type: EOA")
+			}
+		}
+	},
+}
+
+var predictFrom string
+var predictNonce int64
+var predictCount int
+var predictSalt string
+var predictContract string
+var predictArgs []string
+var predictInitCode string
+var predictBuildDir string
+
+var addressPredictCmd = &cobra.Command{
+	Use:   "predict",
+	Short: "compute the address of a contract before deploying it (CREATE or CREATE2 with --salt)",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		from := parseAddress(predictFrom)
+
+		// CREATE2: keccak256(0xff ++ deployer ++ salt ++ keccak256(initCode))[12:]，与 nonce 无关
+		if predictSalt != "" {
+			salt, err := deployer.ParseSalt(predictSalt)
+			if err != nil {
+				log.Fatal(err)
+			}
+			initCode := predictInitCodeBytes()
+			fmt.Println("deployer:", formatAddress(from))
+			fmt.Println("salt:", common.Hash(salt).Hex())
+			fmt.Println("init code hash:", crypto.Keccak256Hash(initCode).Hex())
+			fmt.Println("address:", deployer.Create2Address(from, salt, initCode).Hex())
+			return
+		}
+
+		// CREATE: keccak256(rlp([sender, nonce]))[12:]，不指定 nonce 时取账户下一个 nonce
+		nonce := uint64(predictNonce)
+		if predictNonce < 0 {
+			n, err := dialClient().PendingNonceAt(context.Background(), from)
+			if err != nil {
+				log.Fatal(err)
+			}
+			nonce = n
+		}
+		fmt.Println("sender:", formatAddress(from))
+		for i := 0; i < predictCount; i++ {
+			fmt.Printf("nonce %d: %s\n", nonce+uint64(i), crypto.CreateAddress(from, nonce+uint64(i)).Hex())
+		}
+	},
+}
+
+// predictInitCodeBytes 取 --init-code，或者由 contracts/build 中的 .bin 和构造函数参数拼出来
+func predictInitCodeBytes() []byte {
+	if predictInitCode != "" {
+		code, err := hexutil.Decode(predictInitCode)
+		if err != nil {
+			log.Fatal("invalid --init-code: ", err)
+		}
+		return code
+	}
+	if predictContract == "" {
+		log.Fatal("CREATE2 needs --contract or --init-code")
+	}
+	artifact, err := deployer.LoadArtifact(predictBuildDir, predictContract)
+	if err != nil {
+		log.Fatal(err)
+	}
+	code, err := artifact.InitCode(resolveArgAddresses(artifact.ABI.Constructor.Inputs, predictArgs))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return code
+}
+
+// resolveArgAddresses 把 address 类型参数中的 @alice 之类的名字换成地址
+func resolveArgAddresses(inputs abi.Arguments, values []string) []string {
+	resolved := append([]string{}, values...)
+	for i, input := range inputs {
+		if i < len(resolved) && input.Type.T == abi.AddressTy {
+			resolved[i] = parseAddress(resolved[i]).Hex()
+		}
+	}
+	return resolved
+}
+
+// parseAddress 解析命令行中的地址参数，格式不对或校验和错误时直接退出
+func parseAddress(s string) common.Address {
+	input, err := resolveAddressName(s)
+	if err != nil {
+		log.Fatal(err)
+	}
+	address, err := ethaddr.Parse(input)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return address
+}
+
+func parseAddresses(list []string) []common.Address {
+	addresses := make([]common.Address, len(list))
+	for i, s := range list {
+		addresses[i] = parseAddress(s)
+	}
+	return addresses
+}
+
+// resolveAddressName 把 @alice、token:MTK 之类的名字换成地址簿中的地址，其他输入原样返回
+func resolveAddressName(s string) (string, error) {
+	if !addressbook.IsName(s) {
+		return s, nil
+	}
+	address, err := addressBook().Resolve(s)
+	if err != nil {
+		return "", err
+	}
+	return address.Hex(), nil
+}
+
+func init() {
+	rootCmd.AddCommand(addressCmd)
+	addressCmd.AddCommand(addressCheckCmd, addressPredictCmd)
+
+	flags := addressPredictCmd.Flags()
+	flags.StringVar(&predictFrom, "from", "0xE280029a7867BA5C9154434886c241775ea87e53", "sender for CREATE, deployer/factory contract for CREATE2")
+	flags.Int64Var(&predictNonce, "nonce", -1, "sender nonce, -1 for the next pending nonce")
+	flags.IntVar(&predictCount, "count", 1, "number of consecutive nonces to list")
+	flags.StringVar(&predictSalt, "salt", "", "CREATE2 salt, 0x hex or decimal")
+	flags.StringVar(&predictContract, "contract", "", "contract name in the build dir, e.g. Store")
+	flags.StringSliceVar(&predictArgs, "args", nil, "constructor arguments")
+	flags.StringVar(&predictInitCode, "init-code", "", "init code hex, instead of --contract")
+	flags.StringVar(&predictBuildDir, "build-dir", "contracts/build", "directory with <Name>.bin and <Name>.abi")
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"yunlabs.com/goethereumbook/addressbook"
+)
+
+var bookTags []string
+var bookChain uint64
+
+// AddressBook
+var addressBookCmd = &cobra.Command{
+	Use:   "addressbook",
+	Short: "Labeled addresses: 地址簿，命令行中可用 @label 或 tag:label 代替地址",
+}
+
+var addressBookAddCmd = &cobra.Command{
+	Use:   "add <label> <address>",
+	Short: "add or replace an entry",
+	Args:  cobra.ExactArgs(2),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		book := addressbook.New(loadAddressBookEntries(), 0)
+		err := book.Add(addressbook.Entry{Label: args[0], Address: args[1], Tags: bookTags, Chain: bookChain})
+		if err != nil {
+			log.Fatal(err)
+		}
+		saveAddressBook(book.Entries())
+	},
+}
+
+var addressBookRmCmd = &cobra.Command{
+	Use:   "rm <label>",
+	Short: "remove an entry",
+	Args:  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		book := addressbook.New(loadAddressBookEntries(), 0)
+		if !book.Remove(args[0], bookChain) {
+			log.Fatal(args[0], " is not in the address book")
+		}
+		saveAddressBook(book.Entries())
+	},
+}
+
+var addressBookListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list entries, optionally only those with a tag",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		for _, e := range loadAddressBookEntries() {
+			if cmd.Flags().Changed("chain") && e.Chain != bookChain {
+				continue
+			}
+			if len(bookTags) > 0 && !hasAnyTag(e, bookTags) {
+				continue
+			}
+			scope := "all"
+			if e.Chain != 0 {
+				scope = fmt.Sprintf("chain %d", e.Chain)
+			}
+			fmt.Printf("%-16s %s %-12s %s\n", e.Label, e.Address, scope, strings.Join(e.Tags, ","))
+		}
+	},
+}
+
+var addressBookImportCmd = &cobra.Command{
+	Use:   "import <file.csv>",
+	Short: "import entries from CSV: label,address[,tags separated by ;[,chain]]",
+	Args:  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		f, err := os.Open(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+
+		entries, err := addressbook.ReadCSV(f)
+		if err != nil {
+			log.Fatal(args[0], ": ", err)
+		}
+		book := addressbook.New(loadAddressBookEntries(), 0)
+		for _, e := range entries {
+			if err := book.Add(e); err != nil {
+				log.Fatal(e.Label, ": ", err)
+			}
+		}
+		saveAddressBook(book.Entries())
+		fmt.Println("imported", len(entries), "entries")
+	},
+}
+
+func hasAnyTag(e addressbook.Entry, tags []string) bool {
+	for _, tag := range tags {
+		if e.HasTag(tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func loadAddressBookEntries() []addressbook.Entry {
+	var entries []addressbook.Entry
+	if err := viper.UnmarshalKey("addressbook", &entries); err != nil {
+		log.Fatal("addressbook: ", err)
+	}
+	return entries
+}
+
+var book *addressbook.Book
+
+// addressBook 加载配置中的地址簿。只有存在限定链的条目时才去节点查询 chainID
+func addressBook() *addressbook.Book {
+	if book != nil {
+		return book
+	}
+	entries := loadAddressBookEntries()
+	var chain uint64
+	for _, e := range entries {
+		if e.Chain != 0 {
+			if id, err := dialClient().ChainID(context.Background()); err == nil {
+				chain = id.Uint64()
+			}
+			break
+		}
+	}
+	book = addressbook.New(entries, chain)
+	return book
+}
+
+// saveAddressBook 只改写配置文件中的 addressbook，其他配置和命令行参数不会被写进去
+func saveAddressBook(entries []addressbook.Entry) {
+	file := viper.ConfigFileUsed()
+	if file == "" {
+		file = cfgFile
+	}
+	if file == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			log.Fatal(err)
+		}
+		file = filepath.Join(home, ".goethereumbook.yaml")
+	}
+
+	v := viper.New()
+	v.SetConfigFile(file)
+	if err := v.ReadInConfig(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		log.Fatal(err)
+	}
+	v.Set("addressbook", entries)
+	if err := v.WriteConfigAs(file); err != nil {
+		log.Fatal(err)
+	}
+	viper.Set("addressbook", entries)
+	book = nil
+}
+
+// formatAddress 在已知地址后面加上地址簿中的标签
+func formatAddress(addr common.Address) string {
+	if label := addressBook().Label(addr); label != "" {
+		return addr.Hex() + " (" + label + ")"
+	}
+	return addr.Hex()
+}
+
+// addressLabels 返回地址到标签的映射，用于 JSON 输出
+func addressLabels(addrs ...common.Address) map[common.Address]string {
+	labels := make(map[common.Address]string)
+	for _, addr := range addrs {
+		if label := addressBook().Label(addr); label != "" {
+			labels[addr] = label
+		}
+	}
+	return labels
+}
+
+func init() {
+	rootCmd.AddCommand(addressBookCmd)
+	addressBookCmd.AddCommand(addressBookAddCmd, addressBookRmCmd, addressBookListCmd, addressBookImportCmd)
+
+	addressBookAddCmd.Flags().StringSliceVar(&bookTags, "tag", nil, "tags, e.g. token")
+	addressBookListCmd.Flags().StringSliceVar(&bookTags, "tag", nil, "only entries with one of these tags")
+	for _, c := range []*cobra.Command{addressBookAddCmd, addressBookRmCmd, addressBookListCmd} {
+		c.Flags().Uint64Var(&bookChain, "chain", 0, "chain ID the entry is scoped to, 0 for all networks")
+	}
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+
+	"yunlabs.com/goethereumbook/balances"
+	"yunlabs.com/goethereumbook/output"
+	"yunlabs.com/goethereumbook/units"
+)
+
+var balancesFile string
+var balancesBook bool
+var balancesTags []string
+var balancesKeystore string
+var balancesTokens []string
+var balancesBlock string
+var balancesExport string
+
+// Balances
+var balancesCmd = &cobra.Command{
+	Use:   "balances [address...]",
+	Short: "Snapshot ETH and ERC20 balances of many addresses at a block, tag or time",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+
+		addrs := balancesAddresses(args)
+		if len(addrs) == 0 {
+			log.Fatal("no addresses: pass them as arguments, --file, --book or --keystore-dir")
+		}
+		tokens := parseAddresses(balancesTokens)
+
+		// 先把 --block 解析成具体区块，ETH 和代币余额都在同一个区块读取
+		header, err := blockFinder().Resolve(ctx, balancesBlock)
+		if err != nil {
+			log.Fatal(err)
+		}
+		snap, err := balances.Take(ctx, dialRPC(), dialBatch(), header, addrs, tokens)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for i := range snap.Rows {
+			snap.Rows[i].Label = addressBook().Label(snap.Rows[i].Address)
+		}
+
+		printSnapshot(snap)
+		if balancesExport != "" {
+			exportBalances(balancesExport, snap, func(w io.Writer) error { return balances.WriteCSV(w, snap) })
+		}
+	},
+}
+
+var balancesDiffCmd = &cobra.Command{
+	Use:   "diff <before.json> <after.json>",
+	Short: "compare two snapshots saved with --export",
+	Args:  cobra.ExactArgs(2),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		a, err := balances.Load(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		b, err := balances.Load(args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+		changes := balances.Diff(a, b)
+
+		fmt.Printf("block %d -> %d, %d changes\n", a.Block, b.Block, len(changes))
+		for _, c := range changes {
+			u := units.Unit{Name: c.Asset, Decimals: c.Decimals}
+			delta := units.Format(c.Delta, c.Decimals, units.Exact)
+			if c.Delta.Sign() > 0 {
+				delta = "+" + delta
+			}
+			fmt.Printf("%-42s %-12s %-6s %s -> %s (%s)\n", c.Address.Hex(), c.Label, c.Asset,
+				units.Format(c.Before, c.Decimals, units.Exact), units.FormatUnit(c.After, u, units.Exact), delta)
+		}
+		if balancesExport != "" {
+			exportBalances(balancesExport, changes, func(w io.Writer) error { return balances.WriteDiffCSV(w, changes) })
+		}
+	},
+}
+
+// balancesAddresses 汇总参数、文件、地址簿和 keystore 目录中的地址，去掉重复
+func balancesAddresses(args []string) []common.Address {
+	list := append([]string{}, args...)
+	if balancesFile != "" {
+		list = append(list, readAddressFile(balancesFile)...)
+	}
+	if balancesBook {
+		for _, e := range addressBook().Scoped() {
+			if len(balancesTags) == 0 || hasAnyTag(e, balancesTags) {
+				list = append(list, e.Address)
+			}
+		}
+	}
+
+	var addrs []common.Address
+	seen := make(map[common.Address]bool)
+	add := func(addr common.Address) {
+		if !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	for _, s := range list {
+		add(parseAddress(s))
+	}
+	if balancesKeystore != "" {
+		// 只读取 keystore 文件中的地址，不需要解密
+		ks := keystore.NewKeyStore(balancesKeystore, keystore.LightScryptN, keystore.LightScryptP)
+		for _, account := range ks.Accounts() {
+			add(account.Address)
+		}
+	}
+	return addrs
+}
+
+// readAddressFile 每行一个地址，也可以是 CSV 的第一列，# 开头的行是注释
+func readAddressFile(file string) []string {
+	f, err := os.Open(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	var list []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(strings.SplitN(scanner.Text(), ",", 2)[0])
+		if line == "" || strings.HasPrefix(line, "#") || strings.EqualFold(line, "address") {
+			continue
+		}
+		list = append(list, line)
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+	return list
+}
+
+func printSnapshot(snap *balances.Snapshot) {
+	// 每个地址每种资产一行，raw 是最小单位的整数，amount 按各自的小数位数精确显示
+	block := strconv.FormatUint(snap.Block, 10)
+	var rows []output.Balance
+	for _, row := range snap.Rows {
+		b := output.NewBalance(row.Address, units.ETH, nil, row.ETH)
+		b.Label, b.Block = row.Label, block
+		rows = append(rows, b)
+		for _, t := range snap.Tokens {
+			token := t.Address
+			b := output.NewBalance(row.Address, units.Unit{Name: t.Symbol, Decimals: t.Decimals}, &token, row.Tokens[t.Address])
+			b.Label, b.Block = row.Label, block
+			rows = append(rows, b)
+		}
+	}
+	log.Printf("block %d %s at %s", snap.Block, snap.BlockHash.Hex(), time.Unix(int64(snap.Time), 0).UTC().Format(time.RFC3339))
+	render(rows)
+}
+
+// exportBalances 按扩展名导出：.json 可以再用 balances diff 比较，.csv 方便导入表格
+func exportBalances(file string, v interface{}, writeCSV func(io.Writer) error) {
+	f, err := os.Create(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer f.Close()
+
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(v)
+	case ".csv":
+		err = writeCSV(f)
+	default:
+		log.Fatal("export file must end in .json or .csv: ", file)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(balancesCmd)
+	balancesCmd.AddCommand(balancesDiffCmd)
+
+	balancesCmd.Flags().StringVar(&balancesFile, "file", "", "file with one address per line")
+	balancesCmd.Flags().BoolVar(&balancesBook, "book", false, "include the address book entries")
+	balancesCmd.Flags().StringSliceVar(&balancesTags, "tag", nil, "with --book, only entries with these tags")
+	balancesCmd.Flags().StringVar(&balancesKeystore, "keystore-dir", "", "include the accounts of a keystore directory")
+	balancesCmd.Flags().StringSliceVar(&balancesTokens, "token", nil, "ERC20 tokens to include, address or token:MTK")
+	addBlockFlags(balancesCmd.Flags(), &balancesBlock)
+	balancesCmd.PersistentFlags().StringVar(&balancesExport, "export", "", "also write the result to a .json or .csv file")
+}
@@ -5,8 +5,6 @@ import (
 	"crypto/ecdsa"
 	"fmt"
 	"log"
-	"math"
-	"math/big"
 	"os"
 	"regexp"
 
@@ -14,9 +12,12 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/spf13/cobra"
 	"golang.org/x/crypto/sha3"
+
+	"yunlabs.com/goethereumbook/ethaddr"
+	"yunlabs.com/goethereumbook/output"
+	"yunlabs.com/goethereumbook/units"
 )
 
 var runAccount bool
@@ -24,6 +25,7 @@ var runWallet bool
 var runKeystore bool
 var runCheckAddress bool
 var accountAddress string
+var accountAt string
 
 // Account
 var chapter2Cmd = &cobra.Command{
@@ -42,10 +44,7 @@ var chapter2Cmd = &cobra.Command{
 		// 	fmt.Printf("Non-flag argument: %s\n", arg)
 		// }
 
-		client, err := ethclient.Dial("http://localhost:8545")
-		if err != nil {
-			log.Fatal(err)
-		}
+		client := dialClient()
 
 		// 账户余额
 		if runAccount {
@@ -55,7 +54,7 @@ var chapter2Cmd = &cobra.Command{
 			if accountAddress == "" {
 				accountAddress = "0xE280029a7867BA5C9154434886c241775ea87e53"
 			}
-			account := common.HexToAddress(accountAddress)
+			account := parseAddress(accountAddress) // 严格校验长度和 EIP-55 校验和
 			// fmt.Println(account)              // 0xE280029a7867BA5C9154434886c241775ea87e53
 			// fmt.Println(account.Hex())        // 0xE280029a7867BA5C9154434886c241775ea87e53
 			// fmt.Println(account.Hash().Hex()) // 0x000000000000000000000000e280029a7867ba5c9154434886c241775ea87e53
@@ -63,11 +62,11 @@ var chapter2Cmd = &cobra.Command{
 
 			// 读取一个账户的余额相当简单。调用客户端的BalanceAt方法，给它传递账户地址和可选的区块号。将区块号设置为nil将返回最新的余额。
 			// 传区块号能让您读取该区块时的账户余额。区块号必须是big.Int类型。
-			balance, err := client.BalanceAt(context.Background(), account, nil)
+			// --at 可以写区块号，也可以写时间，如 --at 2024-01-01T00:00:00Z 读取那一刻的余额
+			balance, err := client.BalanceAt(context.Background(), account, resolveBlock(accountAt))
 			if err != nil {
 				log.Fatal(err)
 			}
-			fmt.Println(balance) // 100000000000000000000 wei (100 ether)
 
 			// 传递区块号能让您读取该区块时的账户余额。区块号必须是big.Int类型。
 			// blockNumber := big.NewInt(5532993)
@@ -78,17 +77,23 @@ var chapter2Cmd = &cobra.Command{
 			// fmt.Println(balanceAt)
 
 			// 以太坊中的所有值都是以wei为单位的。wei是以太坊中的最小单位。1 ether = 10^18 wei。
-			fbalance := new(big.Float)
-			fbalance.SetString(balance.String())
-			ethValue := new(big.Float).Quo(fbalance, big.NewFloat(math.Pow10(18))) // wei/10^18
-			fmt.Println(ethValue)                                                  // 100 ether
+			// 用 big.Float 除以 10^18 会丢失精度，units 按字符串精确移动小数点
 
 			// 待处理的账户余额是指账户的余额加上所有待处理的交易的总和。
 			pendingBalance, err := client.PendingBalanceAt(context.Background(), account)
 			if err != nil {
 				log.Fatal(err)
 			}
-			fmt.Println(pendingBalance) // 100000000000000000000
+
+			// 两个余额按 --output 输出成表格、JSON、YAML 或 CSV，raw 是 wei，amount 是精确的 ETH 数量
+			label := addressLabels(account)[account]
+			rows := []output.Balance{
+				output.NewBalance(account, units.ETH, nil, balance),
+				output.NewBalance(account, units.ETH, nil, pendingBalance),
+			}
+			rows[0].Label, rows[0].Block = label, accountAt
+			rows[1].Label, rows[1].Block = label, "pending"
+			render(rows)
 		}
 
 		if runWallet {
@@ -165,6 +170,12 @@ var chapter2Cmd = &cobra.Command{
 			fmt.Printf("is valid: %v\n", re.MatchString("0x323b5d4c32345ced77393b3530b1eed0f346429d")) // is valid: true
 			fmt.Printf("is valid: %v\n", re.MatchString("0xZYXb5d4c32345ced77393b3530b1eed0f346429d")) // is valid: false
 
+			// 正则只检查格式，大小写混合的地址还应该验证 EIP-55 校验和，拼错一个字母就能发现
+			_, err := ethaddr.Parse("0xE280029a7867BA5C9154434886c241775ea87e53")
+			fmt.Printf("is valid: %v\n", err == nil) // is valid: true
+			_, err = ethaddr.Parse("0xE280029a7867BA5C9154434886c241775ea87E53")
+			fmt.Printf("is valid: %v (%v)\n", err == nil, err) // is valid: false ("0xE280...E53": address has an invalid EIP-55 checksum)
+
 			// 检查地址是否为账户或智能合约
 			// 可以确定，若在该地址存储了字节码，该地址是智能合约。
 			// 当地址上没有字节码时，我们知道它不是一个智能合约，它是一个标准的以太坊账户。
@@ -200,6 +211,7 @@ func init() {
 	chapter2Cmd.Flags().BoolVarP(&runCheckAddress, "checkaddress", "c", false, "run check address demo")
 
 	chapter2Cmd.Flags().StringVarP(&accountAddress, "address", "d", "", "account address")
+	chapter2Cmd.Flags().StringVar(&accountAt, "at", "latest", "block number, tag or time for the balance")
 
 	// chapter2Cmd.MarkFlagRequired("account")
 }
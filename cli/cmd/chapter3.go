@@ -12,14 +12,21 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"yunlabs.com/goethereumbook/follower"
+	"yunlabs.com/goethereumbook/output"
+	"yunlabs.com/goethereumbook/trace"
+	"yunlabs.com/goethereumbook/units"
 )
 
 var curBlock int64
+var curAt string
 var runBlock bool
 var runTransaction bool
+var showInternal bool
 var runTransfer bool
 var runTransferToken bool
 var runSubscribe bool
@@ -32,11 +39,17 @@ var chapter3Cmd = &cobra.Command{
 	Short: "Demo code for chapter 3: 交易",
 
 	Run: func(cmd *cobra.Command, args []string) {
-		client, err := ethclient.Dial("http://localhost:8545")
-		if err != nil {
-			log.Fatal(err)
+		// --at 按时间找区块，如 --at 2024-01-01T00:00:00Z，找到的区块号代替 --cur
+		if curAt != "" {
+			header, err := blockFinder().Resolve(context.Background(), curAt)
+			if err != nil {
+				log.Fatal(err)
+			}
+			curBlock = header.Number.Int64()
 		}
 
+		client := dialClient()
+
 		// 生成block 1
 		// ETH转账：以太币数量，gas限额，gas价格，一个随机数(nonce)，接收地址以及可选择性的添加的数据
 		if runTransfer {
@@ -63,7 +76,7 @@ var chapter3Cmd = &cobra.Command{
 			}
 
 			// 设置我们将要转移的ETH数量。
-			value := big.NewInt(1000000000000000000) // in wei (1 eth)
+			value, _ := units.ParseEther("1 ether") // 1000000000000000000 wei
 
 			// ETH转账的燃气应设上限为“21000”单位。
 			gasLimit := uint64(21000) // in units
@@ -114,12 +127,6 @@ var chapter3Cmd = &cobra.Command{
 		// 查询区块
 		if runBlock {
 			// 调用客户端的HeaderByNumber来返回有关一个区块的头信息, 传入nil，它将返回最新的区块头
-			header, err := client.HeaderByNumber(context.Background(), nil)
-			if err != nil {
-				log.Fatal(err)
-			}
-
-			fmt.Println(header.Number.String())
 
 			// 调用客户端的BlockByNumber方法来获得完整区块。您可以读取该区块的所有内容和元数据，例如，区块号，区块时间戳，区块摘要，区块难度以及交易列表等等。
 			// ganache cli客户端启动后，需要先执行go run main.go chapter3 -r，生成第一个区块1，才能查询到
@@ -129,18 +136,16 @@ var chapter3Cmd = &cobra.Command{
 				log.Fatal(err)
 			}
 
-			fmt.Println(block.Number().Uint64())     // 1
-			fmt.Println(block.Time())                // 1692874584
-			fmt.Println(block.Difficulty().Uint64()) // 0
-			fmt.Println(block.Hash().Hex())          // 0x277ae95b482a82ae0a5d88eb3b6ddad136b152b27b234d6740f032ed6f895a07
-			fmt.Println(len(block.Transactions()))   // 1
-
-			// 调用客户端的 Transaction 方法来获取一个区块中的交易
+			// 调用客户端的 TransactionCount 方法来获取一个区块中的交易数量
 			count, err := client.TransactionCount(context.Background(), block.Hash())
 			if err != nil {
 				log.Fatal(err)
 			}
-			fmt.Println(count) // 144
+
+			// 区块号、时间、难度、哈希等带字段名输出，--output json/yaml/csv 方便脚本解析
+			info := output.NewBlock(block.Header(), int(count))
+			info.MinerLabel = addressLabels(info.Miner)[info.Miner]
+			render(info)
 		}
 
 		// 查询 block =1 交易，应该安排在交易之后
@@ -152,26 +157,27 @@ var chapter3Cmd = &cobra.Command{
 				log.Fatal(err)
 			}
 
-			for _, tx := range block.Transactions() {
-				fmt.Println(tx.Hash().Hex())
-				fmt.Println(tx.Value().String())
-				fmt.Println(tx.Gas())
-				fmt.Println(tx.GasPrice().Uint64())
-				fmt.Println(tx.Nonce())
-				fmt.Println(tx.Data())
-				fmt.Println(tx.To().Hex())
-
-				chainID, err := client.ChainID(context.Background())
+			// 每个交易连同收据一起输出，结果写到标准输出，下面其余的演示信息写到标准错误
+			chainID, err := client.ChainID(context.Background())
+			if err != nil {
+				log.Fatal(err)
+			}
+			// 合约内部转出的 ETH 只能从调用追踪中看到，要追踪整个区块，所以只在 --internal 时获取
+			var internal map[common.Hash][]trace.Transfer
+			if showInternal {
+				internal, err = trace.BlockTransfers(context.Background(), dialRPC(), block)
 				if err != nil {
-					log.Fatal(err)
+					log.Println("internal transfers unavailable:", err)
 				}
+			}
 
-				fmt.Println(chainID)      // 1692877060468
-				fmt.Println(tx.ChainId()) // 1692877060468 ???
-
+			var txs []output.Tx
+			for i, tx := range block.Transactions() {
 				// 通过交易获取发送者地址 发送方的地址是从交易的签名中恢复出来的
-				if fromAddress, err := types.Sender(types.NewEIP155Signer(tx.ChainId()), tx); err == nil {
-					fmt.Println(fromAddress.Hex())
+				// LatestSignerForChainID 同时支持 legacy、EIP-2930 和 EIP-1559 交易
+				fromAddress, err := types.Sender(types.LatestSignerForChainID(chainID), tx)
+				if err != nil {
+					log.Fatal(err)
 				}
 				// 以下代码报错, AsMessage方法不存在了
 				// if msg, err := tx.AsMessage(types.NewEIP155Signer(chainID)); err == nil {
@@ -183,16 +189,25 @@ var chapter3Cmd = &cobra.Command{
 					log.Fatal(err)
 				}
 
-				fmt.Println(receipt.Status) // 1
-				fmt.Println(receipt.Logs)   // []
+				info := output.NewTx(tx, fromAddress)
+				info.BlockNumber, info.Index = block.NumberU64(), uint(i)
+				info.Receipt = output.NewReceipt(receipt)
+				info.Internal = internal[tx.Hash()]
+				labels := addressLabels(fromAddress)
+				info.FromLabel = labels[fromAddress]
+				if tx.To() != nil {
+					info.ToLabel = addressLabels(*tx.To())[*tx.To()]
+				}
+				txs = append(txs, info)
 
 				if tx.Hash().Hex() == "0x5d49fcaa394c97ec8a9c3e7bd9e8388d420fb050a52083ca52ff24b3b65bc9c2" {
 					break //Test 仅打印第一个交易
 				}
 			}
+			render(txs)
 
 			// 在不获取块的情况下遍历事务的另一种方法是调用客户端的TransactionInBlock方法。 此方法仅接受块哈希和块内事务的索引值。 您可以调用TransactionCount来了解块中有多少个事务。
-			fmt.Println("TransactionInBlock", block.Hash().Hex()) // 0xe2e3e100b9da3c9bfa94955517285952311e7a23b0889cde1f571006a5f4e6ac
+			log.Println("TransactionInBlock", block.Hash().Hex()) // 0xe2e3e100b9da3c9bfa94955517285952311e7a23b0889cde1f571006a5f4e6ac
 			blockHash := block.Hash()
 			// blockHash := common.HexToHash("0xe2e3e100b9da3c9bfa94955517285952311e7a23b0889cde1f571006a5f4e6ac")
 			count, err := client.TransactionCount(context.Background(), blockHash)
@@ -206,7 +221,7 @@ var chapter3Cmd = &cobra.Command{
 					log.Fatal(err)
 				}
 
-				fmt.Println(tx.Hash().Hex()) // 0x5d49fcaa394c97ec8a9c3e7bd9e8388d420fb050a52083ca52ff24b3b65bc9c2
+				log.Println(tx.Hash().Hex()) // 0x5d49fcaa394c97ec8a9c3e7bd9e8388d420fb050a52083ca52ff24b3b65bc9c2
 				if tx.Hash().Hex() == "0x36368eb4665367100bcb46427e8ac39b7873abfca2015116c478f84642a8812d" {
 					break //Test 仅打印前三个交易
 				}
@@ -219,8 +234,8 @@ var chapter3Cmd = &cobra.Command{
 				log.Fatal(err)
 			}
 
-			fmt.Println("tx: ", tx.Value(), tx.To())
-			fmt.Println(isPending) // false
+			log.Println("tx: ", units.FormatEther(tx.Value()), tx.To())
+			log.Println(isPending) // false
 		}
 
 		// ERC20 Token转账
@@ -262,8 +277,7 @@ var chapter3Cmd = &cobra.Command{
 			paddedAddress := common.LeftPadBytes(toAddress.Bytes(), 32)
 			fmt.Println("paddedAddress", hexutil.Encode(paddedAddress))
 
-			amount := new(big.Int)
-			amount.SetString("1000000000000000000000", 10) // 1000 tokens
+			amount, _ := units.Parse("1000", 18) // 1000 tokens，代币有 18 位小数
 			paddedAmount := common.LeftPadBytes(amount.Bytes(), 32)
 			fmt.Println("paddedAmount", hexutil.Encode(paddedAmount)) // 0x00000000000000000000000000000000000000000000003635c9adc5dea00000
 
@@ -303,36 +317,31 @@ var chapter3Cmd = &cobra.Command{
 		}
 
 		// 订阅，有新block时，打印出来
+		// 直接循环 SubscribeNewHead 会在订阅出错时退出，也不知道新区块头是否接在上一个之后。
+		// 这里改用 follower：通过父哈希检测重组并发出 Added/Removed 事件，websocket 断开时退回 HTTP 轮询并自动重连。
 		if runSubscribe {
-			client, err := ethclient.Dial("ws://localhost:8545")
-			if err != nil {
-				log.Fatal("client ", err)
-			}
+			f := follower.New(client, follower.Config{WSURL: viper.GetString("ws")})
 
-			headers := make(chan *types.Header)
-			sub, err := client.SubscribeNewHead(context.Background(), headers)
-			if err != nil {
-				log.Fatal("sub ", err)
-			}
-
-			for {
-				select {
-				case err := <-sub.Err():
-					log.Fatal("Err ", err)
-				case header := <-headers:
-					fmt.Println(header.Hash().Hex())
-
-					block, err := client.BlockByHash(context.Background(), header.Hash())
-					if err != nil {
-						log.Fatal("block ", err)
-					}
-
-					fmt.Println(block.Hash().Hex())
-					fmt.Println(block.Number().Uint64())
-					fmt.Println(block.Time())
-					fmt.Println(block.Nonce())
-					fmt.Println(len(block.Transactions()))
+			err := f.Run(context.Background(), func(ev follower.Event) error {
+				fmt.Println(ev.Type, ev.Header.Hash().Hex())
+				if ev.Type == follower.Removed {
+					return nil
+				}
+
+				block, err := client.BlockByHash(context.Background(), ev.Header.Hash())
+				if err != nil {
+					return err
 				}
+
+				fmt.Println(block.Hash().Hex())
+				fmt.Println(block.Number().Uint64())
+				fmt.Println(block.Time())
+				fmt.Println(block.Nonce())
+				fmt.Println(len(block.Transactions()))
+				return nil
+			})
+			if err != nil {
+				log.Fatal("follow ", err)
 			}
 		}
 
@@ -355,8 +364,8 @@ var chapter3Cmd = &cobra.Command{
 				log.Fatal(err)
 			}
 
-			value := big.NewInt(1000000000000000000) // in wei (1 eth)
-			gasLimit := uint64(21000)                // in units
+			value, _ := units.ParseEther("1 ether")
+			gasLimit := uint64(21000) // in units
 			gasPrice, err := client.SuggestGasPrice(context.Background())
 			if err != nil {
 				log.Fatal(err)
@@ -416,10 +425,12 @@ func init() {
 	rootCmd.AddCommand(chapter3Cmd)
 
 	chapter3Cmd.Flags().Int64VarP(&curBlock, "cur", "c", 1, "block number")
+	chapter3Cmd.Flags().StringVar(&curAt, "at", "", "block number, tag or time, overrides --cur")
 
 	chapter3Cmd.Flags().BoolVarP(&runTransfer, "transfer", "r", false, "run transfer demo, generate block 1")
 	chapter3Cmd.Flags().BoolVarP(&runBlock, "block", "b", false, "get block 1 info")
 	chapter3Cmd.Flags().BoolVarP(&runTransaction, "transaction", "t", false, "get transaction info from block 1")
+	chapter3Cmd.Flags().BoolVar(&showInternal, "internal", false, "with --transaction, include internal ETH transfers from call traces (needs the debug API)")
 	chapter3Cmd.Flags().BoolVarP(&runTransferToken, "transferToken", "o", false, "run transfer token demo")
 	chapter3Cmd.Flags().BoolVarP(&runSubscribe, "subscribe", "s", false, "run subscribe demo")
 	chapter3Cmd.Flags().BoolVarP(&runRawTransaction, "rawTransaction", "w", false, "run raw transaction demo")
@@ -6,17 +6,17 @@ import (
 	"encoding/hex"
 	"fmt"
 	"log"
-	"math"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
-	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/spf13/cobra"
 
+	"yunlabs.com/goethereumbook/batch"
 	"yunlabs.com/goethereumbook/contracts/store"
 	"yunlabs.com/goethereumbook/contracts/token"
+	"yunlabs.com/goethereumbook/output"
 )
 
 var curAddress string
@@ -24,6 +24,7 @@ var runLoad bool
 var runSetItem bool
 var runCodeAt bool
 var runERC20 bool
+var runMulticall bool
 
 // Client
 var chapter4Cmd = &cobra.Command{
@@ -31,10 +32,7 @@ var chapter4Cmd = &cobra.Command{
 	Short: "Demo code for chapter 4: 智能合约",
 
 	Run: func(cmd *cobra.Command, args []string) {
-		client, err := ethclient.Dial("http://localhost:8545")
-		if err != nil {
-			log.Fatal(err)
-		}
+		client := dialClient()
 
 		if runLoad {
 			address := common.HexToAddress("0x2e144aF3Bde9B518C7C65FBE170c07c888f1fF1a")
@@ -131,7 +129,7 @@ var chapter4Cmd = &cobra.Command{
 				log.Fatal("token", err)
 			}
 
-			address := common.HexToAddress(curAddress)
+			address := parseAddress(curAddress)
 			bal, err := instance.BalanceOf(&bind.CallOpts{}, address)
 			if err != nil {
 				log.Fatal("BalanceOf", err)
@@ -152,17 +150,50 @@ var chapter4Cmd = &cobra.Command{
 				log.Fatal(err)
 			}
 
-			fmt.Printf("name: %s\n", name)         // "name: Golem Network"
-			fmt.Printf("symbol: %s\n", symbol)     // "symbol: GNT"
-			fmt.Printf("decimals: %v\n", decimals) // "decimals: 18"
+			totalSupply, err := instance.TotalSupply(&bind.CallOpts{})
+			if err != nil {
+				log.Fatal(err)
+			}
 
-			fmt.Printf("wei: %s\n", bal) // "wei: 74605500647408739782407023"
+			// 代币信息和余额一起输出，余额按 decimals 精确换算，不经过浮点数
+			info := output.TokenInfo{Address: tokenAddress, Name: name, Symbol: symbol, Decimals: decimals, TotalSupply: totalSupply}
+			balance := output.NewBalance(address, info.Unit(), &tokenAddress, bal)
+			balance.Label = addressLabels(address)[address]
+			render(struct {
+				Token   output.TokenInfo `json:"token"`
+				Balance output.Balance   `json:"balance"`
+			}{info, balance}) // balance.amount: 74605500.647408739782407023
+		}
 
-			fbal := new(big.Float)
-			fbal.SetString(bal.String())
-			value := new(big.Float).Quo(fbal, big.NewFloat(math.Pow10(int(decimals))))
+		// 上面每个查询都是一次 eth_call，地址多了就很慢。
+		// 用 batch 把这些调用合并成一次 Multicall3.aggregate3 调用，链上没有 Multicall3 时改用 JSON-RPC 批量请求
+		if runMulticall {
+			tokenAddress := common.HexToAddress("0xC28614fEcD3109EFf192DD3cABc7ac9b82C7eD11")
+			tokenABI, err := token.TokenMetaData.GetAbi()
+			if err != nil {
+				log.Fatal(err)
+			}
 
-			fmt.Printf("balance: %f", value)
+			address := parseAddress(curAddress)
+			calls := []*batch.Call{
+				batch.NewCall(tokenAddress, tokenABI, "balanceOf", address),
+				batch.NewCall(tokenAddress, tokenABI, "name"),
+				batch.NewCall(tokenAddress, tokenABI, "symbol"),
+				batch.NewCall(tokenAddress, tokenABI, "decimals"),
+			}
+			bc := dialBatch()
+			if err := bc.Do(nil, calls); err != nil {
+				log.Fatal(err)
+			}
+			viaMulticall, _ := bc.UsesMulticall(nil)
+			fmt.Println("multicall:", viaMulticall) // false 表示用的是 JSON-RPC 批量请求
+
+			for _, call := range calls {
+				if call.Err != nil {
+					log.Fatal(call.Method, ": ", call.Err)
+				}
+				fmt.Printf("%s: %v\n", call.Method, call.Out[0])
+			}
 		}
 
 	},
@@ -177,4 +208,5 @@ func init() {
 	chapter4Cmd.Flags().BoolVarP(&runSetItem, "set", "s", false, "set item")
 	chapter4Cmd.Flags().BoolVarP(&runCodeAt, "code", "c", false, "get code")
 	chapter4Cmd.Flags().BoolVarP(&runERC20, "erc20", "e", false, "erc20 token")
+	chapter4Cmd.Flags().BoolVarP(&runMulticall, "multicall", "m", false, "read erc20 token in one batched call")
 }
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"yunlabs.com/goethereumbook/batch"
+	"yunlabs.com/goethereumbook/blocks"
+)
+
+// 新增的命令不再写死节点地址，统一从 --rpc/--ws 或配置文件读取
+func dialClient() *ethclient.Client {
+	client, err := ethclient.Dial(viper.GetString("rpc"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return client
+}
+
+// 需要调用 ethclient 没有封装的方法时(如 eth_getProof、debug_*)，直接使用 rpc.Client
+func dialRPC() *rpc.Client {
+	client, err := rpc.Dial(viper.GetString("rpc"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return client
+}
+
+// dialBatch 返回合并只读调用的客户端，Multicall3 地址可以在配置文件的 multicall 中修改(如本地链上自己部署的)
+func dialBatch() *batch.Client {
+	address := batch.Multicall3Address
+	if s := viper.GetString("multicall"); s != "" {
+		address = parseAddress(s)
+	}
+	return batch.New(dialRPC(), address)
+}
+
+var finder *blocks.Finder
+
+func blockFinder() *blocks.Finder {
+	if finder == nil {
+		finder = blocks.NewFinder(dialClient())
+	}
+	return finder
+}
+
+// addBlockFlags 添加 --block/-b 和 --at 两个等价的参数，可以写区块号、latest/safe/finalized/earliest
+// 或时间(2024-01-01T00:00:00Z、2024-01-01、@1704067200)，时间对应该时刻或之后的第一个区块
+func addBlockFlags(flags *pflag.FlagSet, p *string) {
+	flags.StringVarP(p, "block", "b", "latest", "block number, latest/safe/finalized/earliest, or a time")
+	flags.StringVar(p, "at", "latest", "same as --block, e.g. --at 2024-01-01T00:00:00Z")
+}
+
+// resolveBlock 把区块参数解析成区块号，latest 返回 nil
+func resolveBlock(s string) *big.Int {
+	if s == "" || s == "latest" {
+		return nil
+	}
+	header, err := blockFinder().Resolve(context.Background(), s)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return header.Number
+}
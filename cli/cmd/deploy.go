@@ -0,0 +1,148 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/spf13/cobra"
+
+	"yunlabs.com/goethereumbook/contracts/factory"
+	"yunlabs.com/goethereumbook/deployer"
+)
+
+var deployArgs []string
+var deploySalt string
+var deployFactory string
+var deployBuildDir string
+var deployForce bool
+var deployForward bool
+
+// Deploy
+var deployCmd = &cobra.Command{
+	Use:   "deploy <contract>",
+	Short: "Deploy a contract from contracts/build, with --salt through the CREATE2 factory at the same address on every chain",
+	Args:  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		client := dialClient()
+		ctx := context.Background()
+		opts := transactOpts(cmd, client)
+
+		artifact, err := deployer.LoadArtifact(deployBuildDir, args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		ctorArgs := resolveArgAddresses(artifact.ABI.Constructor.Inputs, deployArgs)
+
+		// 普通 CREATE 部署，地址取决于部署账户和 nonce
+		if deploySalt == "" {
+			values, err := deployer.ParseArgs(artifact.ABI.Constructor.Inputs, ctorArgs)
+			if err != nil {
+				log.Fatal(err)
+			}
+			address, tx, _, err := bind.DeployContract(opts, *artifact.ABI, artifact.Bin, client, values...)
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println("tx:", tx.Hash().Hex())
+			if _, err := bind.WaitDeployed(ctx, client, tx); err != nil {
+				log.Fatal(err)
+			}
+			fmt.Printf("deployed %s at %s\n", artifact.Name, address.Hex())
+			return
+		}
+
+		// CREATE2 部署，地址只取决于工厂合约、salt 和 init code，重复执行时已部署则跳过
+		salt, err := deployer.ParseSalt(deploySalt)
+		if err != nil {
+			log.Fatal(err)
+		}
+		factoryAddr := parseAddress(deployFactory)
+		switch {
+		case deployForward:
+			// 先通过代理部署转发工厂(已部署则跳过)，再通过它部署，铸给工厂的代币会转给当前账户
+			forwarder, tx, err := deployer.DeployForwarder(ctx, client, opts, factoryAddr)
+			if tx != nil {
+				fmt.Println("forwarder tx:", tx.Hash().Hex())
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+			fmt.Println("forwarder:", forwarder.Hex())
+			factoryAddr = forwarder
+		case deployer.CreditsSender(artifact.ABI):
+			// 构造函数里的 msg.sender 是工厂合约，记给部署者的余额或所有权会永远留在工厂名下
+			if !deployForce {
+				log.Fatalf("%s looks like it credits msg.sender in its constructor, which is the factory when deploying with --salt: "+
+					"the balance or ownership would be lost. Use --forward for tokens, or --force to deploy anyway", artifact.Name)
+			}
+			fmt.Fprintf(os.Stderr, "warning: %s is constructed by the factory %s, anything credited to msg.sender stays there\n", artifact.Name, factoryAddr.Hex())
+		}
+		initCode, err := artifact.InitCode(ctorArgs)
+		if err != nil {
+			log.Fatal(err)
+		}
+		address, tx, err := deployer.Create2(ctx, client, opts, factoryAddr, salt, initCode)
+		if tx != nil {
+			fmt.Println("tx:", tx.Hash().Hex())
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if tx == nil {
+			fmt.Printf("%s already deployed at %s, skipped\n", artifact.Name, address.Hex())
+			return
+		}
+		fmt.Printf("deployed %s at %s\n", artifact.Name, address.Hex())
+	},
+}
+
+var deployFactoryCmd = &cobra.Command{
+	Use:   "factory",
+	Short: "deploy the deterministic deployment proxy, or the bundled factory if the node refuses it",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		client := dialClient()
+		ctx := context.Background()
+		opts := transactOpts(cmd, client)
+
+		err := deployer.DeployProxy(ctx, client, opts)
+		if err == nil {
+			fmt.Println("factory:", deployer.ProxyAddress.Hex())
+			return
+		}
+		if !errors.Is(err, deployer.ErrUnprotectedRejected) {
+			log.Fatal(err)
+		}
+
+		// 节点不接受没有链 ID 的预签名交易(geth 需要 --rpc.allow-unprotected-txs)，
+		// 退而用当前账户部署同样的工厂代码。这样部署出来的地址取决于账户和 nonce，各链之间不一定相同
+		fmt.Println(err)
+		address, tx, _, err := factory.DeployFactory(opts, client)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err := bind.WaitDeployed(ctx, client, tx); err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("factory:", address.Hex())
+		fmt.Println("deployed with CREATE from", opts.From.Hex(), "- pass --factory", address.Hex())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(deployCmd)
+	deployCmd.AddCommand(deployFactoryCmd)
+
+	addSignerFlags(deployCmd.PersistentFlags())
+	deployCmd.Flags().StringSliceVar(&deployArgs, "args", nil, "constructor arguments")
+	deployCmd.Flags().StringVar(&deploySalt, "salt", "", "deploy with CREATE2 using this salt, 0x hex or decimal")
+	deployCmd.Flags().StringVar(&deployFactory, "factory", deployer.ProxyAddress.Hex(), "CREATE2 factory address")
+	deployCmd.Flags().BoolVar(&deployForce, "force", false, "with --salt, deploy even if the constructor credits msg.sender, which is the factory")
+	deployCmd.Flags().BoolVar(&deployForward, "forward", false, "with --salt, deploy through a forwarding factory that passes the minted tokens on to the sender")
+	deployCmd.Flags().StringVar(&deployBuildDir, "build-dir", "contracts/build", "directory with <Name>.bin and <Name>.abi")
+}
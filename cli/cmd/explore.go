@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+
+	"yunlabs.com/goethereumbook/explore"
+	"yunlabs.com/goethereumbook/history"
+	"yunlabs.com/goethereumbook/index"
+	"yunlabs.com/goethereumbook/registry"
+)
+
+var exploreSimulated bool
+var exploreDB string
+var exploreABIDir string
+var explorePoll time.Duration
+var exploreBacklog uint64
+var exploreHistoryBlocks uint64
+
+// Explore
+var exploreCmd = &cobra.Command{
+	Use:   "explore",
+	Short: "Interactive terminal block explorer: 终端区块浏览器",
+	Long: `Show the latest blocks as they arrive and drill into blocks, transactions,
+receipts and logs with Enter; Esc goes back and / jumps to a block number,
+transaction hash or address. Calldata and events of the bundled and
+--abi-dir ABIs are decoded. The address view shows the balance, nonce, code
+and the transactions of the address, read from the local index (--db) when
+it exists, otherwise from the last --history-blocks blocks.
+
+--simulated runs against an in-memory chain with Multicall3 and a few
+accounts sending each other ETH, no node needed.`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		reg := registry.Default()
+		if err := reg.LoadDir(exploreABIDir); err != nil {
+			log.Fatal(err)
+		}
+		cfg := explore.Config{
+			Registry:      reg,
+			Poll:          explorePoll,
+			Backlog:       exploreBacklog,
+			HistoryBlocks: exploreHistoryBlocks,
+			Label:         addressBook().Label,
+		}
+
+		if exploreSimulated {
+			demo, err := explore.NewDemo(3)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer demo.Close()
+			// 模拟链上的地址不在地址簿里，直接给它们起名字
+			labels := map[common.Address]string{demo.Multicall: "Multicall3"}
+			for i, addr := range demo.Accounts {
+				labels[addr] = fmt.Sprintf("demo account %d", i)
+			}
+			cfg.Backend, cfg.Label = demo, func(addr common.Address) string { return labels[addr] }
+			go func() {
+				if err := demo.Run(ctx, explorePoll); err != nil && err != context.Canceled {
+					log.Println(err)
+				}
+			}()
+		} else {
+			cfg.Backend = dialClient()
+			if info, err := os.Stat(exploreDB); err == nil && info.IsDir() {
+				db, err := index.Open(exploreDB, true)
+				if err != nil {
+					log.Fatal(err)
+				}
+				ix := index.New(db, nil)
+				defer ix.Close()
+				cfg.History = &history.IndexSource{Index: ix}
+			}
+		}
+
+		if err := explore.New(cfg).Run(ctx); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exploreCmd)
+
+	flags := exploreCmd.Flags()
+	flags.BoolVar(&exploreSimulated, "simulated", false, "explore an in-memory simulated chain instead of --rpc")
+	flags.StringVar(&exploreDB, "db", "./chaindata", "index database directory for address history, used when it exists")
+	flags.StringVar(&exploreABIDir, "abi-dir", "contracts/build", "directory with extra *.abi files for decoding calls and events")
+	flags.DurationVar(&explorePoll, "poll", 2*time.Second, "how often to check for new blocks (and mine one with --simulated)")
+	flags.Uint64Var(&exploreBacklog, "backlog", 50, "recent blocks shown at start")
+	flags.Uint64Var(&exploreHistoryBlocks, "history-blocks", 1000, "blocks scanned for the address history without an index")
+}
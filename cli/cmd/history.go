@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+
+	"yunlabs.com/goethereumbook/batch"
+	"yunlabs.com/goethereumbook/contracts/token"
+	"yunlabs.com/goethereumbook/history"
+	"yunlabs.com/goethereumbook/index"
+	"yunlabs.com/goethereumbook/output"
+	"yunlabs.com/goethereumbook/registry"
+	"yunlabs.com/goethereumbook/units"
+)
+
+var historyFrom string
+var historyTo string
+var historyDB string
+var historyScan bool
+var historyInternal bool
+var historyPage int
+var historyLimit int
+var historyAsc bool
+var historyExport string
+
+// scanRange 是没有指定 --from 时逐块扫描的区块数
+const scanRange = 1000
+
+var accountsHistoryCmd = &cobra.Command{
+	Use:   "history <address>",
+	Short: "list the transactions of an address: sent, received, contracts created, ERC20 and internal transfers",
+	Long: `List the transactions where the address is the sender, the recipient, the
+creator of a contract, a party of an ERC20 Transfer or, with --internal, of
+an internal ETH transfer. Each transaction is shown as one or more entries
+with direction, counterparty, value, the fee paid by the sender and status.
+
+The local index (--db) is used when it exists, otherwise the blocks are
+fetched from the node one by one, by default the last 1000 of them.`,
+	Args: cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		addr := parseAddress(args[0])
+		if historyPage < 1 {
+			log.Fatal("--page starts at 1")
+		}
+
+		var source history.Source
+		from, to := uint64(0), uint64(math.MaxUint64)
+		if info, err := os.Stat(historyDB); err == nil && info.IsDir() && !historyScan {
+			db, err := index.Open(historyDB, true)
+			if err != nil {
+				log.Fatal(err)
+			}
+			ix := index.New(db, nil)
+			defer ix.Close()
+			source = &history.IndexSource{Index: ix}
+			log.Printf("reading the local index %s", historyDB)
+		} else {
+			latest, err := blockFinder().Resolve(ctx, "latest")
+			if err != nil {
+				log.Fatal(err)
+			}
+			to = latest.Number.Uint64()
+			if to > scanRange {
+				from = to - scanRange
+			}
+			scanner := &history.Scanner{
+				Client: dialClient(),
+				Progress: func(done, total uint64) {
+					if done%100 == 0 || done == total {
+						fmt.Fprintf(os.Stderr, "\rscanned %d/%d blocks", done, total)
+					}
+					if done == total {
+						fmt.Fprintln(os.Stderr)
+					}
+				},
+			}
+			if historyInternal {
+				scanner.Tracer = dialRPC()
+			}
+			source = scanner
+		}
+		if historyFrom != "" {
+			from = resolveBlock(historyFrom).Uint64()
+		}
+		if historyTo != "latest" {
+			to = resolveBlock(historyTo).Uint64()
+		}
+		if _, ok := source.(*history.Scanner); ok {
+			log.Printf("scanning blocks %d to %d", from, to)
+		}
+
+		refs, err := source.Refs(ctx, addr, from, to)
+		if err != nil {
+			log.Fatal(err)
+		}
+		history.Sort(refs, historyAsc)
+
+		// 导出时写入全部交易，屏幕上只显示一页
+		page := refs
+		pages := 1
+		if historyLimit > 0 {
+			if len(refs) > historyLimit {
+				pages = (len(refs) + historyLimit - 1) / historyLimit
+			}
+			start := (historyPage - 1) * historyLimit
+			if start > len(refs) {
+				start = len(refs)
+			}
+			end := start + historyLimit
+			if end > len(refs) {
+				end = len(refs)
+			}
+			page = refs[start:end]
+		}
+		if historyExport != "" {
+			page = refs
+		}
+
+		txs := make([]*history.Tx, len(page))
+		for i, ref := range page {
+			if txs[i], err = source.Load(ctx, ref); err != nil {
+				log.Fatal(err)
+			}
+		}
+		b := &history.Builder{Registry: registry.Default(), Token: tokenUnits(txs)}
+		var entries []history.Entry
+		for _, tx := range txs {
+			entries = append(entries, b.Entries(addr, tx)...)
+		}
+		var counterparties []common.Address
+		for _, e := range entries {
+			counterparties = append(counterparties, e.Counterparty)
+		}
+		labels := addressLabels(counterparties...)
+		for i := range entries {
+			entries[i].CounterpartyLabel = labels[entries[i].Counterparty]
+		}
+
+		if historyExport != "" {
+			exportHistory(historyExport, entries)
+			log.Printf("%d transactions, %d entries written to %s", len(refs), len(entries), historyExport)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "page %d/%d, %d transactions\n", historyPage, pages, len(refs))
+		render(entries)
+	},
+}
+
+// tokenUnits 一次批量读取交易中发出 ERC20 Transfer 事件的合约的 symbol 和 decimals，
+// 读取失败的(不是标准 ERC20)显示地址、按 0 位小数
+func tokenUnits(txs []*history.Tx) func(common.Address) units.Unit {
+	known := make(map[common.Address]units.Unit)
+	var tokens []common.Address
+	for _, tx := range txs {
+		for _, l := range tx.Receipt.Logs {
+			// 和 history.Builder.Entries 一样只看 Transfer 事件，其他合约的日志用不到代币信息
+			if len(l.Topics) != 3 || l.Topics[0] != history.TransferTopic {
+				continue
+			}
+			if _, ok := known[l.Address]; !ok {
+				known[l.Address] = units.Unit{Name: l.Address.Hex()[:10], Decimals: 0}
+				tokens = append(tokens, l.Address)
+			}
+		}
+	}
+	if len(tokens) > 0 {
+		tokenABI, err := token.TokenMetaData.GetAbi()
+		if err != nil {
+			log.Fatal(err)
+		}
+		var calls []*batch.Call
+		for _, t := range tokens {
+			calls = append(calls, batch.NewCall(t, tokenABI, "symbol"), batch.NewCall(t, tokenABI, "decimals"))
+		}
+		if err := dialBatch().Do(&bind.CallOpts{}, calls); err != nil {
+			log.Printf("token metadata: %v", err)
+		} else {
+			for i, t := range tokens {
+				symbol, decimals := calls[2*i], calls[2*i+1]
+				if symbol.Err == nil && decimals.Err == nil {
+					known[t] = units.Unit{Name: symbol.Out[0].(string), Decimals: decimals.Out[0].(uint8)}
+				}
+			}
+		}
+	}
+	return func(t common.Address) units.Unit {
+		return known[t]
+	}
+}
+
+// exportHistory 按扩展名选择格式：.json、.yaml/.yml 或 .csv
+func exportHistory(file string, entries []history.Entry) {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), ".")
+	if ext == "yml" {
+		ext = "yaml"
+	}
+	f, err := output.ParseFormat(ext)
+	if err != nil || f == output.Table {
+		log.Fatal("export file must end in .json, .yaml or .csv: ", file)
+	}
+	w, err := os.Create(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer w.Close()
+	if err := output.Write(w, f, entries); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func init() {
+	accountsCmd.AddCommand(accountsHistoryCmd)
+
+	flags := accountsHistoryCmd.Flags()
+	flags.StringVar(&historyFrom, "from", "", "first block or time (default: 0 with the index, latest-1000 when scanning)")
+	flags.StringVar(&historyTo, "to", "latest", "last block or time")
+	flags.StringVar(&historyDB, "db", "./chaindata", "index database directory, used when it exists")
+	flags.BoolVar(&historyScan, "scan", false, "scan blocks from the node even when the index exists")
+	flags.BoolVar(&historyInternal, "internal", false, "when scanning, include internal ETH transfers from call traces (needs the debug API)")
+	flags.IntVar(&historyPage, "page", 1, "page to show")
+	flags.IntVar(&historyLimit, "limit", 25, "transactions per page, 0 for all")
+	flags.BoolVar(&historyAsc, "asc", false, "oldest first")
+	flags.StringVar(&historyExport, "export", "", "write every entry to a .json, .yaml or .csv file instead of printing a page")
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"yunlabs.com/goethereumbook/follower"
+	"yunlabs.com/goethereumbook/index"
+	"yunlabs.com/goethereumbook/output"
+)
+
+var indexDB string
+var indexFrom uint64
+var indexPoll time.Duration
+var indexInternal bool
+var queryFrom uint64
+var queryTo uint64
+var queryLimit int
+
+// Index
+var indexCmd = &cobra.Command{
+	Use:   "index",
+	Short: "Local chain indexer: 把区块、交易、收据和日志存入本地数据库",
+}
+
+var indexRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "follow the chain and keep the local index up to date",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		db, err := index.Open(indexDB, false)
+		if err != nil {
+			log.Fatal(err)
+		}
+		client := dialClient()
+		ix := index.New(db, client)
+		if indexInternal {
+			ix.TraceTransfers(dialRPC())
+		}
+		defer ix.Close()
+
+		f := follower.New(client, follower.Config{
+			WSURL:        viper.GetString("ws"),
+			PollInterval: indexPoll,
+			From:         new(big.Int).SetUint64(indexFrom),
+		})
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		if err := ix.Follow(ctx, f); err != nil && err != context.Canceled {
+			log.Fatal(err)
+		}
+	},
+}
+
+var indexQueryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "query the local index without touching the node",
+}
+
+var indexQueryBlockCmd = &cobra.Command{
+	Use:   "block <number|hash>",
+	Short: "show an indexed block",
+	Args:  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ix := openIndex(true)
+		defer ix.Close()
+
+		var (
+			block *index.Block
+			err   error
+		)
+		if number, perr := strconv.ParseUint(args[0], 10, 64); perr == nil {
+			block, err = ix.BlockByNumber(number)
+		} else {
+			block, err = ix.BlockByHash(common.HexToHash(args[0]))
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		renderAs(output.JSON, struct {
+			*index.Block
+			MinerLabel string `json:"minerLabel,omitempty"`
+		}{block, addressLabels(block.Miner)[block.Miner]})
+	},
+}
+
+var indexQueryTxCmd = &cobra.Command{
+	Use:   "tx <hash>",
+	Short: "show an indexed transaction with its receipt",
+	Args:  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ix := openIndex(true)
+		defer ix.Close()
+
+		tx, err := ix.Transaction(common.HexToHash(args[0]))
+		if err != nil {
+			log.Fatal(err)
+		}
+		info := output.NewTx(tx.Tx, tx.From)
+		info.BlockNumber, info.Index = tx.BlockNumber, tx.Index
+		if tx.Receipt != nil {
+			info.Receipt = output.NewReceipt(tx.Receipt)
+		}
+		info.Internal = tx.Internal
+		addrs := []common.Address{tx.From}
+		if tx.Tx.To() != nil {
+			addrs = append(addrs, *tx.Tx.To())
+		}
+		labels := addressLabels(addrs...)
+		info.FromLabel = labels[tx.From]
+		if tx.Tx.To() != nil {
+			info.ToLabel = labels[*tx.Tx.To()]
+		}
+		renderAs(output.JSON, info)
+	},
+}
+
+var indexQueryAddressCmd = &cobra.Command{
+	Use:   "address <address>",
+	Short: "list transactions touching an address",
+	Args:  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ix := openIndex(true)
+		defer ix.Close()
+
+		hashes, err := ix.AddressTxs(parseAddress(args[0]), queryFrom, queryTo, queryLimit)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, hash := range hashes {
+			fmt.Println(hash.Hex())
+		}
+	},
+}
+
+var indexQueryTopicCmd = &cobra.Command{
+	Use:   "topic <topic>",
+	Short: "list logs carrying a topic",
+	Args:  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ix := openIndex(true)
+		defer ix.Close()
+
+		logs, err := ix.TopicLogs(common.HexToHash(args[0]), queryFrom, queryTo, queryLimit)
+		if err != nil {
+			log.Fatal(err)
+		}
+		events := make([]output.Event, len(logs))
+		for i, l := range logs {
+			events[i] = output.NewEvent(l)
+		}
+		renderAs(output.JSON, events)
+	},
+}
+
+func openIndex(readonly bool) *index.Indexer {
+	db, err := index.Open(indexDB, readonly)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return index.New(db, nil)
+}
+
+func init() {
+	rootCmd.AddCommand(indexCmd)
+	indexCmd.AddCommand(indexRunCmd)
+	indexCmd.AddCommand(indexQueryCmd)
+	indexQueryCmd.AddCommand(indexQueryBlockCmd, indexQueryTxCmd, indexQueryAddressCmd, indexQueryTopicCmd)
+
+	indexCmd.PersistentFlags().StringVar(&indexDB, "db", "./chaindata", "index database directory")
+
+	indexRunCmd.Flags().Uint64Var(&indexFrom, "from", 0, "first block to index when the database is empty")
+	indexRunCmd.Flags().DurationVar(&indexPoll, "poll", 2*time.Second, "http polling interval when websocket is unavailable")
+	indexRunCmd.Flags().BoolVar(&indexInternal, "internal", false, "record internal ETH transfers from call traces (needs the debug API)")
+
+	indexQueryCmd.PersistentFlags().Uint64Var(&queryFrom, "from", 0, "first block")
+	indexQueryCmd.PersistentFlags().Uint64Var(&queryTo, "to", math.MaxUint64, "last block")
+	indexQueryCmd.PersistentFlags().IntVar(&queryLimit, "limit", 0, "max results, 0 for all")
+}
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/spf13/cobra"
+
+	"yunlabs.com/goethereumbook/addressbook"
+	"yunlabs.com/goethereumbook/events"
+	"yunlabs.com/goethereumbook/output"
+	"yunlabs.com/goethereumbook/registry"
+)
+
+var logsFrom string
+var logsTo string
+var logsAddresses []string
+var logsEvents []string
+var logsTopics [4][]string
+var logsChunk uint64
+var logsLimit int
+var logsABIs []string
+var logsABIDir string
+
+// logsRange 是没有指定 --from 时查询的区块数
+const logsRange = 1000
+
+// Logs
+var logsCmd = &cobra.Command{
+	Use:   "logs",
+	Short: "Filter and decode event logs: 查询并解码事件日志",
+	Long: `Query event logs by contract address and topics over a block range and
+decode them against the bundled ABIs, the *.abi files in --abi-dir and the
+files given with --abi, matched by topic0.
+
+The range is queried in chunks of --chunk blocks; when the node answers that
+a chunk has too many results it is halved and retried. --event takes an event
+signature, Transfer(address,address,uint256), or the name of a known event,
+Transfer or ERC20.Transfer, and sets topic0. --topic1..3 take 32-byte hex
+values, addresses (also @name and token:SYMBOL from the address book) or
+decimal numbers. Repeating a flag matches any of its values.`,
+	Example: `  logs --address token:MTK --event Transfer --from 0
+  logs --event ERC20.Transfer --topic2 0xE280029a7867BA5C9154434886c241775ea87e53 --output json`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		reg := registry.Default()
+		if err := reg.LoadDir(logsABIDir); err != nil {
+			log.Fatal(err)
+		}
+		for _, file := range logsABIs {
+			if err := reg.LoadFile(file); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		q := ethereum.FilterQuery{Addresses: parseAddresses(logsAddresses)}
+		topics := make([][]common.Hash, 4)
+		for _, name := range logsEvents {
+			topic, err := reg.EventTopic(name)
+			if err != nil {
+				log.Fatal(err)
+			}
+			topics[0] = append(topics[0], topic)
+		}
+		for i, values := range logsTopics {
+			for _, s := range values {
+				topics[i] = append(topics[i], parseTopic(s))
+			}
+		}
+		// 去掉末尾没有条件的位置，中间没有条件的位置留空表示任意值
+		for len(topics) > 0 && len(topics[len(topics)-1]) == 0 {
+			topics = topics[:len(topics)-1]
+		}
+		q.Topics = topics
+
+		latest, err := blockFinder().Resolve(ctx, logsTo)
+		if err != nil {
+			log.Fatal(err)
+		}
+		to, from := latest.Number.Uint64(), uint64(0)
+		if to >= logsRange {
+			from = to - logsRange + 1
+		}
+		if logsFrom != "" {
+			header, err := blockFinder().Resolve(ctx, logsFrom)
+			if err != nil {
+				log.Fatal(err)
+			}
+			from = header.Number.Uint64()
+		}
+
+		var found []types.Log
+		fetcher := &events.Fetcher{
+			Client: dialClient(),
+			Chunk:  logsChunk,
+			Progress: func(done, total uint64) {
+				fmt.Fprintf(os.Stderr, "\rscanned %d/%d blocks, %d logs", done, total, len(found))
+				if done == total {
+					fmt.Fprintln(os.Stderr)
+				}
+			},
+		}
+		err = fetcher.Fetch(ctx, q, from, to, func(logs []types.Log) error {
+			found = append(found, logs...)
+			if logsLimit > 0 && len(found) >= logsLimit {
+				found = found[:logsLimit]
+				fmt.Fprintln(os.Stderr)
+				return events.ErrStop
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		list := make([]output.Event, len(found))
+		decoded := make([]*registry.Log, len(found))
+		for i := range found {
+			list[i], decoded[i] = events.Decode(reg, &found[i])
+		}
+		if outputFormat(output.Table) != output.Table {
+			renderAs(output.Table, list)
+			return
+		}
+		printLogs(list, decoded)
+	},
+}
+
+// printLogs 每条日志先写一行位置和合约，再逐行写参数的名字、类型、是否 indexed 和值；
+// 无法解码的日志写出原始的 topics 和 data
+func printLogs(list []output.Event, decoded []*registry.Log) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for i, ev := range list {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "block %d  tx %s  log %d  %s\n", ev.BlockNumber, ev.TxHash.Hex(), ev.LogIndex, formatAddress(ev.Address))
+		d := decoded[i]
+		if d == nil {
+			fmt.Fprintln(w, "  unknown event")
+			for j, topic := range ev.Topics {
+				fmt.Fprintf(w, "    topic%d\t%s\n", j, topic.Hex())
+			}
+			fmt.Fprintf(w, "    data\t%s\n", hexutil.Encode(ev.Data))
+			continue
+		}
+		fmt.Fprintf(w, "  %s.%s\n", d.Contract, d.Event.Name)
+		for j, arg := range d.Event.Inputs {
+			name := arg.Name
+			if name == "" {
+				name = fmt.Sprintf("arg%d", j)
+			}
+			indexed := ""
+			if arg.Indexed {
+				indexed = "indexed"
+			}
+			value := registry.FormatValue(d.Args[j])
+			if addr, ok := d.Args[j].(common.Address); ok {
+				value = formatAddress(addr)
+			}
+			fmt.Fprintf(w, "    %s\t%s\t%s\t%s\n", name, arg.Type, indexed, value)
+		}
+	}
+	w.Flush()
+}
+
+// parseTopic 把 topic 参数换成 32 字节：十六进制左补零，地址簿名字换成地址，十进制数按 uint256
+func parseTopic(s string) common.Hash {
+	if addressbook.IsName(s) {
+		return common.BytesToHash(parseAddress(s).Bytes())
+	}
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		b, err := hexutil.Decode(s)
+		if err != nil || len(b) > common.HashLength {
+			log.Fatalf("invalid topic %s", s)
+		}
+		return common.BytesToHash(b)
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok || n.Sign() < 0 || n.BitLen() > 256 {
+		log.Fatalf("invalid topic %s", s)
+	}
+	return common.BigToHash(n)
+}
+
+func init() {
+	rootCmd.AddCommand(logsCmd)
+
+	flags := logsCmd.Flags()
+	flags.StringVar(&logsFrom, "from", "", fmt.Sprintf("first block, number or time (default the last %d blocks)", logsRange))
+	flags.StringVar(&logsTo, "to", "latest", "last block, number or time")
+	flags.StringSliceVar(&logsAddresses, "address", nil, "contract addresses, also @name and token:SYMBOL")
+	// 签名里有逗号，不能用 StringSlice
+	flags.StringArrayVar(&logsEvents, "event", nil, "event signature or name, sets topic0")
+	for i := range logsTopics {
+		flags.StringSliceVar(&logsTopics[i], fmt.Sprintf("topic%d", i), nil, fmt.Sprintf("values of topic %d", i))
+	}
+	flags.Uint64Var(&logsChunk, "chunk", events.DefaultChunk, "blocks per eth_getLogs query, halved when the node reports too many results")
+	flags.IntVar(&logsLimit, "limit", 0, "stop after this many logs, 0 for all")
+	flags.StringSliceVar(&logsABIs, "abi", nil, "extra ABI files for decoding")
+	flags.StringVar(&logsABIDir, "abi-dir", "contracts/build", "directory with extra *.abi files for decoding")
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"yunlabs.com/goethereumbook/mempool"
+	"yunlabs.com/goethereumbook/units"
+)
+
+var mempoolFrom []string
+var mempoolTo []string
+var mempoolMinValue string
+var mempoolMethods []string
+var mempoolABIDir string
+var mempoolDropAfter time.Duration
+
+// Mempool
+var mempoolCmd = &cobra.Command{
+	Use:   "mempool",
+	Short: "Pending transaction monitor: 监控交易池中的待打包交易",
+}
+
+var mempoolWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "watch pending transactions until they are mined or dropped",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		// 订阅 newPendingTransactions 只能走 websocket
+		ws, err := rpc.Dial(viper.GetString("ws"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer ws.Close()
+
+		w := mempool.NewWatcher(ws, dialClient())
+		w.DropAfter = mempoolDropAfter
+		w.Filter = mempoolFilter()
+		if mempoolABIDir != "" {
+			if err := w.Registry.LoadDir(mempoolABIDir); err != nil {
+				log.Fatal(err)
+			}
+		}
+		w.OnUpdate = printPendingTx
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		err = w.Run(ctx)
+		fmt.Println(w.Stats())
+		if err != nil && err != context.Canceled {
+			log.Fatal(err)
+		}
+	},
+}
+
+func mempoolFilter() mempool.Filter {
+	var filter mempool.Filter
+	filter.From = parseAddresses(mempoolFrom)
+	filter.To = parseAddresses(mempoolTo)
+	if mempoolMinValue != "" {
+		filter.MinValue = parseEther("min-value", mempoolMinValue)
+	}
+	for _, method := range mempoolMethods {
+		filter.Selectors = append(filter.Selectors, parseSelector(method))
+	}
+	return filter
+}
+
+// parseSelector 接受 4 字节的十六进制选择器或函数签名，如 transfer(address,uint256)
+func parseSelector(method string) []byte {
+	if strings.HasPrefix(method, "0x") {
+		sel, err := hexutil.Decode(method)
+		if err != nil || len(sel) != 4 {
+			log.Fatal("invalid method selector: ", method)
+		}
+		return sel
+	}
+	return crypto.Keccak256([]byte(method))[:4]
+}
+
+func printPendingTx(tx *mempool.Tx) {
+	to := "create"
+	if tx.Tx.To() != nil {
+		to = formatAddress(*tx.Tx.To())
+	}
+	switch tx.Status {
+	case mempool.StatusPending:
+		fmt.Printf("pending %s from %s to %s value %s nonce %d\n", tx.Tx.Hash().Hex(), formatAddress(tx.From), to, units.FormatEther(tx.Tx.Value()), tx.Tx.Nonce())
+		if tx.Call != nil {
+			fmt.Println("  call", tx.Call)
+		}
+	case mempool.StatusMined:
+		fmt.Printf("mined   %s in block %d after %v\n", tx.Tx.Hash().Hex(), tx.Block, tx.Waited.Round(time.Millisecond))
+	case mempool.StatusDropped:
+		fmt.Printf("dropped %s after %v: %s\n", tx.Tx.Hash().Hex(), tx.Waited.Round(time.Millisecond), tx.Reason)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(mempoolCmd)
+	mempoolCmd.AddCommand(mempoolWatchCmd)
+
+	mempoolWatchCmd.Flags().StringSliceVar(&mempoolFrom, "from", nil, "only sender addresses")
+	mempoolWatchCmd.Flags().StringSliceVar(&mempoolTo, "to", nil, "only recipient addresses")
+	mempoolWatchCmd.Flags().StringVar(&mempoolMinValue, "min-value", "", "minimum value, e.g. \"0.5 ether\" or \"100gwei\" (wei without a unit)")
+	mempoolWatchCmd.Flags().StringSliceVar(&mempoolMethods, "method", nil, "method selector (0xa9059cbb) or signature (transfer(address,uint256))")
+	mempoolWatchCmd.Flags().StringVar(&mempoolABIDir, "abi-dir", "contracts/build", "directory with extra *.abi files for decoding calldata")
+	mempoolWatchCmd.Flags().DurationVar(&mempoolDropAfter, "drop-after", 10*time.Minute, "consider a tx dropped after waiting this long")
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+
+	"yunlabs.com/goethereumbook/ethaddr"
+	"yunlabs.com/goethereumbook/notify"
+	"yunlabs.com/goethereumbook/output"
+	"yunlabs.com/goethereumbook/registry"
+)
+
+var notifyRules string
+var notifyCheckpoint string
+var notifyFrom string
+var notifyPoll time.Duration
+var notifyABIDir string
+var notifyDryRun bool
+
+// Notify
+var notifyCmd = &cobra.Command{
+	Use:   "notify",
+	Short: "Run webhooks and commands on chain events: 按规则监听链上事件并通知",
+	Long: `Check every confirmed block against the rules of a YAML file and fire
+their actions. A rule matches one of:
+
+  event     logs of some contracts, an event by signature or name, and
+            conditions on its decoded arguments
+  transfer  transactions sending at least an amount of ETH
+  balance   an ETH balance dropping below or rising above a threshold
+
+Actions post the notification as JSON to a webhook, signed with HMAC-SHA256
+when a secret is set and retried with backoff, or run a shell command with
+the JSON on stdin and NOTIFY_* environment variables. The last processed
+block is saved in --checkpoint, so a restart continues where it stopped.
+See notify/rules.go for the rule file format.`,
+	Example: `  notify --rules notify.yaml
+  notify --rules notify.yaml --from 500 --dry-run`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		cfg, err := notify.LoadConfig(notifyRules)
+		if err != nil {
+			log.Fatal(err)
+		}
+		reg := registry.Default()
+		if err := reg.LoadDir(notifyABIDir); err != nil {
+			log.Fatal(err)
+		}
+		opts := notify.Options{
+			Registry:   reg,
+			Checkpoint: notifyCheckpoint,
+			Poll:       notifyPoll,
+			DryRun:     notifyDryRun,
+			Print:      func(n *notify.Notification) { renderAs(output.JSON, n) },
+			Label:      addressBook().Label,
+		}
+		if notifyFrom != "" {
+			opts.From = resolveBlock(notifyFrom)
+		}
+		// 试运行不改检查点，可以反复用 --from 重放历史区块
+		if notifyDryRun {
+			opts.Checkpoint = ""
+		}
+
+		n, err := notify.New(dialClient(), cfg, resolveAddress, opts)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := n.Run(ctx); err != nil && ctx.Err() == nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// resolveAddress 同 parseAddress，出错时返回错误而不是退出
+func resolveAddress(s string) (common.Address, error) {
+	input, err := resolveAddressName(s)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return ethaddr.Parse(input)
+}
+
+func init() {
+	rootCmd.AddCommand(notifyCmd)
+
+	flags := notifyCmd.Flags()
+	flags.StringVar(&notifyRules, "rules", "notify.yaml", "YAML rule file")
+	flags.StringVar(&notifyCheckpoint, "checkpoint", "notify.checkpoint.json", "file keeping the last processed block")
+	flags.StringVar(&notifyFrom, "from", "", "first block when there is no checkpoint, number or time (default the latest confirmed block)")
+	flags.DurationVar(&notifyPoll, "poll", 2*time.Second, "how often to check for new blocks")
+	flags.StringVar(&notifyABIDir, "abi-dir", "contracts/build", "directory with extra *.abi files for decoding events")
+	flags.BoolVar(&notifyDryRun, "dry-run", false, "print the notifications instead of running the actions, without a checkpoint")
+}
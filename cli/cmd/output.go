@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"log"
+	"os"
+
+	"github.com/spf13/viper"
+
+	"yunlabs.com/goethereumbook/output"
+)
+
+// render 按 --output 输出结构化结果，默认是对齐的表格
+func render(v interface{}) {
+	renderAs(output.Table, v)
+}
+
+// renderAs 同 render，未指定 --output 时使用 def。index query 等原来输出 JSON 的命令默认仍是 JSON
+func renderAs(def output.Format, v interface{}) {
+	if err := output.Write(os.Stdout, outputFormat(def), v); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// outputFormat 返回 --output 指定的格式，未指定时返回 def
+func outputFormat(def output.Format) output.Format {
+	s := viper.GetString("output")
+	if s == "" {
+		return def
+	}
+	f, err := output.ParseFormat(s)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return f
+}
@@ -63,6 +63,12 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.goethereumbook.yaml)")
+	rootCmd.PersistentFlags().String("rpc", "http://localhost:8545", "node http endpoint")
+	rootCmd.PersistentFlags().String("ws", "ws://localhost:8545", "node websocket endpoint")
+	viper.BindPFlag("rpc", rootCmd.PersistentFlags().Lookup("rpc"))
+	viper.BindPFlag("ws", rootCmd.PersistentFlags().Lookup("ws"))
+	rootCmd.PersistentFlags().String("output", "", "output format: table, json, yaml or csv (config: output)")
+	viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output"))
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
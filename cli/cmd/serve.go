@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+
+	"yunlabs.com/goethereumbook/monitor"
+)
+
+var serveAddr string
+var metricsBook bool
+var metricsTags []string
+var metricsTokens []string
+var metricsNamespace string
+var metricsTTL time.Duration
+
+// Serve
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Long running HTTP services: HTTP 服务",
+}
+
+var serveMetricsCmd = &cobra.Command{
+	Use:   "metrics [address...]",
+	Short: "Prometheus /metrics for account balances, nonces, pending txs, tokens and the node",
+	Long: `Serve /metrics in the Prometheus text format with the ETH and --token
+balances, nonces and pending transaction counts of the given accounts (and
+the address book with --book), the TotalSupply of the tokens, the latest
+block height and age, and the latency and errors of the requests to the
+node. Values are read when scraped, at most once per --ttl, in one JSON-RPC
+batch and one Multicall batch; scrapes in between get the cached values.`,
+	Example: `  serve metrics 0xE280029a7867BA5C9154434886c241775ea87e53 --token token:MTK
+  serve metrics --book --tag hot --addr :9190 --ttl 30s`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		accounts := metricsAccounts(args)
+		if len(accounts) == 0 {
+			log.Fatal("no accounts: pass them as arguments or use --book")
+		}
+		exporter, err := monitor.New(dialRPC(), dialBatch(), monitor.Config{
+			Accounts:  accounts,
+			Tokens:    parseAddresses(metricsTokens),
+			Namespace: metricsNamespace,
+			TTL:       metricsTTL,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", exporter)
+		server := &http.Server{Addr: serveAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		go func() {
+			<-ctx.Done()
+			shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			server.Shutdown(shutdown)
+		}()
+
+		log.Printf("serving metrics of %d accounts and %d tokens on http://%s/metrics", len(accounts), len(metricsTokens), serveAddr)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal(err)
+		}
+	},
+}
+
+// metricsAccounts 合并参数和地址簿中的地址，去掉重复的
+func metricsAccounts(args []string) []common.Address {
+	list := append([]string{}, args...)
+	if metricsBook {
+		for _, e := range addressBook().Scoped() {
+			if len(metricsTags) == 0 || hasAnyTag(e, metricsTags) {
+				list = append(list, e.Address)
+			}
+		}
+	}
+	var accounts []common.Address
+	seen := make(map[common.Address]bool)
+	for _, addr := range parseAddresses(list) {
+		if !seen[addr] {
+			seen[addr] = true
+			accounts = append(accounts, addr)
+		}
+	}
+	return accounts
+}
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.AddCommand(serveMetricsCmd)
+
+	serveCmd.PersistentFlags().StringVar(&serveAddr, "addr", "localhost:9190", "listen address")
+
+	flags := serveMetricsCmd.Flags()
+	flags.BoolVar(&metricsBook, "book", false, "also export the addresses of the address book")
+	flags.StringSliceVar(&metricsTags, "tag", nil, "with --book, only entries with these tags")
+	flags.StringSliceVar(&metricsTokens, "token", nil, "ERC20 tokens for balances and TotalSupply, address or token:MTK")
+	flags.StringVar(&metricsNamespace, "namespace", "goethbook", "prefix of the metric names")
+	flags.DurationVar(&metricsTTL, "ttl", 15*time.Second, "how long scraped values are cached")
+}
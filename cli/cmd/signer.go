@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"context"
+	"log"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+
+	"yunlabs.com/goethereumbook/signer"
+)
+
+// addSignerFlags 给需要签名的命令加上 --key/--keystore/--password，
+// 未指定时读取配置文件中的 signer.key、signer.keystore、signer.password
+func addSignerFlags(flags *pflag.FlagSet) {
+	flags.String("key", "", "hex private key (config: signer.key)")
+	flags.String("keystore", "", "keystore file (config: signer.keystore)")
+	flags.String("password", "", "keystore password (config: signer.password)")
+}
+
+func loadSigner(cmd *cobra.Command) *signer.KeySigner {
+	get := func(name string) string {
+		if f := cmd.Flags().Lookup(name); f != nil && f.Changed {
+			return f.Value.String()
+		}
+		return viper.GetString("signer." + name)
+	}
+	s, err := signer.Load(get("key"), get("keystore"), get("password"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	return s
+}
+
+// transactOpts 用配置的签名者构造发送交易用的 TransactOpts，nonce、gas 由 bind 自动填充
+func transactOpts(cmd *cobra.Command, client *ethclient.Client) *bind.TransactOpts {
+	chainID, err := client.ChainID(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	opts, err := bind.NewKeyedTransactorWithChainID(loadSigner(cmd).PrivateKey(), chainID)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return opts
+}
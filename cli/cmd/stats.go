@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+
+	"yunlabs.com/goethereumbook/output"
+	"yunlabs.com/goethereumbook/stats"
+	"yunlabs.com/goethereumbook/units"
+)
+
+var statsFrom string
+var statsTo string
+var statsTop int
+var statsWorkers int
+var statsWidth int
+var statsExport string
+
+// statsRange 是没有指定 --from 时统计的区块数
+const statsRange = 100
+
+// Stats
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Chain statistics: 链上统计",
+}
+
+var statsGasCmd = &cobra.Command{
+	Use:   "gas",
+	Short: "gas utilization, base fee, priority fee percentiles, tx types and top gas consumers over a block range",
+	Long: `Fetch every block of the range with its receipts and show the gas used
+against the gas limit, the base fee and the priority fees (effective tips per
+gas) paid, as sparklines, followed by the transaction type mix and the
+contracts that consumed the most gas. --output csv prints one row per block,
+json and yaml the whole report.`,
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		latest, err := blockFinder().Resolve(ctx, statsTo)
+		if err != nil {
+			log.Fatal(err)
+		}
+		to, from := latest.Number.Uint64(), uint64(0)
+		if to >= statsRange {
+			from = to - statsRange + 1
+		}
+		if statsFrom != "" {
+			header, err := blockFinder().Resolve(ctx, statsFrom)
+			if err != nil {
+				log.Fatal(err)
+			}
+			from = header.Number.Uint64()
+		}
+
+		report, err := stats.Collect(ctx, dialClient(), dialRPC(), from, to, stats.Options{
+			Workers: statsWorkers,
+			Top:     statsTop,
+			Progress: func(done, total uint64) {
+				if done%50 == 0 || done == total {
+					fmt.Fprintf(os.Stderr, "\rfetched %d/%d blocks", done, total)
+				}
+				if done == total {
+					fmt.Fprintln(os.Stderr)
+				}
+			},
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		var addrs []common.Address
+		for _, c := range report.Consumers {
+			addrs = append(addrs, c.Address)
+		}
+		labels := addressLabels(addrs...)
+		for i := range report.Consumers {
+			report.Consumers[i].Label = labels[report.Consumers[i].Address]
+		}
+
+		if statsExport != "" {
+			exportStats(statsExport, report)
+		}
+		switch f := outputFormat(output.Table); f {
+		case output.Table:
+			printGasStats(report)
+		case output.CSV:
+			renderAs(f, report.Blocks)
+		default:
+			renderAs(f, report)
+		}
+	},
+}
+
+// printGasStats 输出汇总、每个区块指标的折线(sparkline)和 gas 消耗排行
+func printGasStats(r *stats.Report) {
+	s := r.Summary
+	fmt.Printf("blocks %d-%d: %d blocks, %d txs, %s gas used, utilization %.1f%%\n",
+		s.From, s.To, s.Blocks, s.Txs, units.Format(new(big.Int).SetUint64(s.GasUsed), 0, units.Options{Group: true}), s.Utilization)
+
+	var utilization, baseFee, tip, txs []float64
+	for _, b := range r.Blocks {
+		utilization = append(utilization, b.Utilization)
+		baseFee = append(baseFee, gweiFloat(b.BaseFee))
+		tip = append(tip, gweiFloat(b.PriorityP50))
+		txs = append(txs, float64(b.Txs))
+	}
+	fmt.Println()
+	printSpark("utilization %", utilization)
+	printSpark("base fee gwei", baseFee)
+	printSpark("tip p50 gwei", tip)
+	printSpark("txs", txs)
+
+	fmt.Println()
+	if s.BaseFeeAvg != nil {
+		fmt.Printf("base fee: min %s, avg %s, max %s gwei\n", gwei(s.BaseFeeMin), gwei(s.BaseFeeAvg), gwei(s.BaseFeeMax))
+	}
+	var tips []string
+	for i, p := range stats.Percentiles {
+		tips = append(tips, fmt.Sprintf("p%.0f %s", p, gwei(s.Priority[i])))
+	}
+	fmt.Printf("priority fee: %s gwei\n", strings.Join(tips, ", "))
+	mix := func(n int) string {
+		if s.Txs == 0 {
+			return "0"
+		}
+		return fmt.Sprintf("%d (%.1f%%)", n, float64(n)/float64(s.Txs)*100)
+	}
+	fmt.Printf("tx types: legacy %s, access list (2930) %s, dynamic fee (1559) %s, blob (4844) %s\n",
+		mix(s.Legacy), mix(s.AccessList), mix(s.DynamicFee), mix(s.Blob))
+
+	if len(r.Consumers) > 0 {
+		fmt.Println("\ntop gas consumers:")
+		render(r.Consumers)
+	}
+}
+
+func printSpark(name string, values []float64) {
+	lo, hi := values[0], values[0]
+	for _, v := range values {
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+	fmt.Printf("%-14s %s  %.4g .. %.4g\n", name, stats.Sparkline(values, statsWidth), lo, hi)
+}
+
+// gwei 按 gwei 显示手续费，测试链上只有几 wei 的费用也能看出来
+func gwei(wei *big.Int) string {
+	if wei == nil {
+		return "-"
+	}
+	return units.Format(wei, units.Gwei.Decimals, units.Exact)
+}
+
+func gweiFloat(wei *big.Int) float64 {
+	if wei == nil {
+		return 0
+	}
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), big.NewFloat(1e9)).Float64()
+	return f
+}
+
+// exportStats 按扩展名导出：.csv 每个区块一行，.json/.yaml 是完整的报告
+func exportStats(file string, r *stats.Report) {
+	ext := strings.TrimPrefix(strings.ToLower(filepath.Ext(file)), ".")
+	if ext == "yml" {
+		ext = "yaml"
+	}
+	f, err := output.ParseFormat(ext)
+	if err != nil || f == output.Table {
+		log.Fatal("export file must end in .csv, .json or .yaml: ", file)
+	}
+	w, err := os.Create(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer w.Close()
+
+	// CSV 只能是扁平的行，导出每个区块的统计；JSON/YAML 导出完整报告
+	var v interface{} = r
+	if f == output.CSV {
+		v = r.Blocks
+	}
+	if err := output.Write(w, f, v); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(statsCmd)
+	statsCmd.AddCommand(statsGasCmd)
+
+	flags := statsGasCmd.Flags()
+	flags.StringVar(&statsFrom, "from", "", "first block or time (default: the last 100 blocks)")
+	flags.StringVar(&statsTo, "to", "latest", "last block or time")
+	flags.IntVar(&statsTop, "top", 10, "number of top gas consumers, 0 for all")
+	flags.IntVar(&statsWorkers, "workers", 8, "blocks fetched concurrently")
+	flags.IntVar(&statsWidth, "width", 60, "sparkline width in characters")
+	flags.StringVar(&statsExport, "export", "", "also write the per-block rows to a .csv file, or the report to .json/.yaml")
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/spf13/cobra"
+
+	"yunlabs.com/goethereumbook/addressbook"
+	"yunlabs.com/goethereumbook/registry"
+	"yunlabs.com/goethereumbook/storage"
+)
+
+var storageLayout string
+var storageBlock string
+var storageRaw bool
+
+// Storage
+var storageCmd = &cobra.Command{
+	Use:   "storage",
+	Short: "Compute storage slots from the solc storage layout and read raw storage",
+}
+
+var storageLayoutCmd = &cobra.Command{
+	Use:   "layout",
+	Short: "list the state variables and their slots",
+
+	Run: func(cmd *cobra.Command, args []string) {
+		layout := loadStorageLayout()
+		for _, v := range layout.Storage {
+			fmt.Printf("%-16s slot %-4s offset %-2d %s\n", v.Label, v.Slot, v.Offset, layout.Types[v.Type].Label)
+		}
+	},
+}
+
+var storageSlotCmd = &cobra.Command{
+	Use:   "slot <path>",
+	Short: "compute the slot of a variable, e.g. balanceOf[0xE280...] or allowance[0xA][0xB]",
+	Args:  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		loc, err := loadStorageLayout().Resolve(resolvePathNames(args[0]))
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println("slot:", loc.Slot.Hex())
+		fmt.Println("offset:", loc.Offset)
+		fmt.Println("type:", loc.Type.Label)
+	},
+}
+
+var storageReadCmd = &cobra.Command{
+	Use:   "read <contract> <path>...",
+	Short: "read and decode variables from a contract's storage",
+	Args:  cobra.MinimumNArgs(2),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		client := dialClient()
+		layout := loadStorageLayout()
+		contract := parseAddress(args[0])
+
+		block := resolveBlock(storageBlock)
+
+		for _, path := range args[1:] {
+			loc, err := layout.Resolve(resolvePathNames(path))
+			if err != nil {
+				log.Fatal(err)
+			}
+			if storageRaw {
+				word, err := client.StorageAt(context.Background(), contract, loc.Slot, block)
+				if err != nil {
+					log.Fatal(err)
+				}
+				fmt.Printf("%s: %s\n", path, common.BytesToHash(word).Hex())
+				continue
+			}
+			value, err := storage.Read(context.Background(), client, layout, contract, loc, block)
+			if err != nil {
+				log.Fatal(path, ": ", err)
+			}
+			fmt.Printf("%s: %s\n", path, formatStorageValue(value))
+		}
+	},
+}
+
+// loadStorageLayout 既可以传布局文件路径，也可以只写合约名(Store、ERC20)，从 contracts/build 中查找
+func loadStorageLayout() *storage.Layout {
+	file := storageLayout
+	if _, err := os.Stat(file); err != nil && !strings.ContainsAny(file, `/\`) {
+		file = filepath.Join("contracts", "build", file+"_storage.json")
+	}
+	layout, err := storage.LoadLayout(file)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return layout
+}
+
+// resolvePathNames 把路径中 [@alice]、[token:MTK] 形式的键换成地址簿中的地址
+func resolvePathNames(path string) string {
+	parts := strings.Split(path, "[")
+	for i, part := range parts[1:] {
+		end := strings.IndexByte(part, ']')
+		if end < 0 || !addressbook.IsName(part[:end]) {
+			continue
+		}
+		if addr, err := addressBook().Resolve(part[:end]); err == nil {
+			parts[i+1] = addr.Hex() + part[end:]
+		}
+	}
+	return strings.Join(parts, "[")
+}
+
+func formatStorageValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf("%q", v)
+	case []storage.Member:
+		parts := make([]string, len(v))
+		for i, m := range v {
+			parts[i] = m.Name + ": " + formatStorageValue(m.Value)
+		}
+		return "{" + strings.Join(parts, ", ") + "}"
+	}
+	return registry.FormatValue(value)
+}
+
+func init() {
+	rootCmd.AddCommand(storageCmd)
+	storageCmd.AddCommand(storageLayoutCmd, storageSlotCmd, storageReadCmd)
+
+	storageCmd.PersistentFlags().StringVarP(&storageLayout, "layout", "l", "ERC20", "storage layout file, or a contract name in contracts/build")
+
+	addBlockFlags(storageReadCmd.Flags(), &storageBlock)
+	storageReadCmd.Flags().BoolVar(&storageRaw, "raw", false, "print the raw 32-byte word instead of decoding")
+}
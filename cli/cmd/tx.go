@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/spf13/cobra"
+
+	"yunlabs.com/goethereumbook/output"
+	"yunlabs.com/goethereumbook/registry"
+	"yunlabs.com/goethereumbook/trace"
+)
+
+var txTraceFile string
+var txTraceSave string
+var txABIDir string
+var txTransfersBlock string
+
+var txCmd = &cobra.Command{
+	Use:   "tx",
+	Short: "Transaction inspection: 交易分析",
+}
+
+var txTraceCmd = &cobra.Command{
+	Use:   "trace [hash]",
+	Short: "show the call tree and state diff of a transaction via debug_traceTransaction",
+	Long: `Trace a transaction with the callTracer and prestateTracer and print the
+nested calls, decoded against the bundled and --abi-dir ABIs, followed by the
+state diff. Nodes without the debug API only yield the top level call from
+the receipt. --trace-file replays a trace saved with --save (or the bare
+callTracer output) without a node.`,
+	Args: cobra.MaximumNArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		var t *trace.Trace
+		if txTraceFile != "" {
+			var err error
+			if t, err = trace.Load(txTraceFile); err != nil {
+				log.Fatal(err)
+			}
+		} else {
+			if len(args) == 0 {
+				log.Fatal("need a transaction hash or --trace-file")
+			}
+			t = fetchTrace(common.HexToHash(args[0]))
+		}
+		if txTraceSave != "" {
+			if err := trace.Save(txTraceSave, t); err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		reg := registry.Default()
+		if err := reg.LoadDir(txABIDir); err != nil {
+			log.Fatal(err)
+		}
+		trace.Annotate(t.Calls, reg)
+		changes := t.StateDiff.Changes()
+
+		// 表格模式画调用树；json/yaml 输出完整的树，csv 每个调用一行
+		switch outputFormat(output.Table) {
+		case output.Table:
+			trace.WriteTree(os.Stdout, t.Calls, formatAddress)
+			if t.StateDiff != nil {
+				fmt.Println()
+				render(changes)
+			}
+		case output.CSV:
+			render(trace.Flatten(t.Calls))
+		default:
+			render(struct {
+				Hash      common.Hash      `json:"hash"`
+				Calls     *trace.CallFrame `json:"calls"`
+				StateDiff []trace.Change   `json:"stateDiff,omitempty"`
+			}{t.Hash, t.Calls, changes})
+		}
+	},
+}
+
+var txTransfersCmd = &cobra.Command{
+	Use:   "transfers [hash]",
+	Short: "list internal ETH transfers of a transaction, or of every transaction in a block with --block",
+	Long: `List the ETH moved by contracts inside transactions: CALLs with value,
+CREATEs funding the new contract and SELFDESTRUCTs, taken from callTracer
+traces. The value of the transaction itself is not included. Needs the
+debug API.`,
+	Args: cobra.MaximumNArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx := context.Background()
+		transfers := []trace.Transfer{}
+		switch {
+		case len(args) == 1:
+			hash := common.HexToHash(args[0])
+			t, err := trace.Fetch(ctx, dialRPC(), hash)
+			if err != nil {
+				log.Fatal(err)
+			}
+			transfers = trace.Transfers(hash, t.Calls)
+		case cmd.Flags().Changed("block") || cmd.Flags().Changed("at"):
+			header, err := blockFinder().Resolve(ctx, txTransfersBlock)
+			if err != nil {
+				log.Fatal(err)
+			}
+			block, err := dialClient().BlockByHash(ctx, header.Hash())
+			if err != nil {
+				log.Fatal(err)
+			}
+			byTx, err := trace.BlockTransfers(ctx, dialRPC(), block)
+			if err != nil {
+				log.Fatal(err)
+			}
+			// 按交易在区块中的顺序输出
+			for _, tx := range block.Transactions() {
+				transfers = append(transfers, byTx[tx.Hash()]...)
+			}
+		default:
+			log.Fatal("need a transaction hash or --block")
+		}
+		render(transfers)
+	},
+}
+
+// fetchTrace 从节点获取 trace；没有 debug API 时退回到交易和收据，只能看到最外层调用
+func fetchTrace(hash common.Hash) *trace.Trace {
+	ctx := context.Background()
+	t, err := trace.Fetch(ctx, dialRPC(), hash)
+	if err == nil {
+		if t.StateDiff == nil {
+			log.Println("prestateTracer is not available, no state diff")
+		}
+		return t
+	}
+	if !errors.Is(err, trace.ErrNoDebug) {
+		log.Fatal(err)
+	}
+	log.Printf("%v; showing the top level call from the receipt only", err)
+
+	client := dialClient()
+	tx, _, err := client.TransactionByHash(ctx, hash)
+	if err != nil {
+		log.Fatal(err)
+	}
+	receipt, err := client.TransactionReceipt(ctx, hash)
+	if err != nil {
+		log.Fatal(err)
+	}
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	from, err := types.Sender(types.LatestSignerForChainID(chainID), tx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return &trace.Trace{Hash: hash, Calls: trace.FromReceipt(tx, from, receipt)}
+}
+
+func init() {
+	rootCmd.AddCommand(txCmd)
+	txCmd.AddCommand(txTraceCmd, txTransfersCmd)
+
+	txTraceCmd.Flags().StringVar(&txTraceFile, "trace-file", "", "read the trace from a file instead of the node")
+	txTraceCmd.Flags().StringVar(&txTraceSave, "save", "", "save the fetched trace for --trace-file")
+	txTraceCmd.Flags().StringVar(&txABIDir, "abi-dir", "contracts/build", "directory with extra *.abi files for decoding calls")
+
+	addBlockFlags(txTransfersCmd.Flags(), &txTransfersBlock)
+}
@@ -0,0 +1,17 @@
+package cmd
+
+import (
+	"log"
+	"math/big"
+
+	"yunlabs.com/goethereumbook/units"
+)
+
+// parseEther 解析 ETH 金额参数，可以带单位("0.5 ether"、"30gwei")，不带单位时是 wei
+func parseEther(name, s string) *big.Int {
+	v, err := units.ParseEther(s)
+	if err != nil {
+		log.Fatalf("invalid --%s: %v", name, err)
+	}
+	return v
+}
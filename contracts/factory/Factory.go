@@ -0,0 +1,224 @@
+// Code generated - DO NOT EDIT.
+// This file is a generated binding and any manual changes will be lost.
+
+package factory
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var (
+	_ = errors.New
+	_ = big.NewInt
+	_ = strings.NewReader
+	_ = ethereum.NotFound
+	_ = bind.Bind
+	_ = common.Big1
+	_ = types.BloomLookup
+	_ = event.NewSubscription
+	_ = abi.ConvertType
+)
+
+// FactoryMetaData contains all meta data concerning the Factory contract.
+var FactoryMetaData = &bind.MetaData{
+	ABI: "[{\"stateMutability\":\"payable\",\"type\":\"fallback\"}]",
+	Bin: "0x604580600e600039806000f350fe7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe03601600081602082378035828234f58015156039578182fd5b8082525050506014600cf3",
+}
+
+// FactoryABI is the input ABI used to generate the binding from.
+// Deprecated: Use FactoryMetaData.ABI instead.
+var FactoryABI = FactoryMetaData.ABI
+
+// FactoryBin is the compiled bytecode used for deploying new contracts.
+// Deprecated: Use FactoryMetaData.Bin instead.
+var FactoryBin = FactoryMetaData.Bin
+
+// DeployFactory deploys a new Ethereum contract, binding an instance of Factory to it.
+func DeployFactory(auth *bind.TransactOpts, backend bind.ContractBackend) (common.Address, *types.Transaction, *Factory, error) {
+	parsed, err := FactoryMetaData.GetAbi()
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	if parsed == nil {
+		return common.Address{}, nil, nil, errors.New("GetABI returned nil")
+	}
+
+	address, tx, contract, err := bind.DeployContract(auth, *parsed, common.FromHex(FactoryBin), backend)
+	if err != nil {
+		return common.Address{}, nil, nil, err
+	}
+	return address, tx, &Factory{FactoryCaller: FactoryCaller{contract: contract}, FactoryTransactor: FactoryTransactor{contract: contract}, FactoryFilterer: FactoryFilterer{contract: contract}}, nil
+}
+
+// Factory is an auto generated Go binding around an Ethereum contract.
+type Factory struct {
+	FactoryCaller     // Read-only binding to the contract
+	FactoryTransactor // Write-only binding to the contract
+	FactoryFilterer   // Log filterer for contract events
+}
+
+// FactoryCaller is an auto generated read-only Go binding around an Ethereum contract.
+type FactoryCaller struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// FactoryTransactor is an auto generated write-only Go binding around an Ethereum contract.
+type FactoryTransactor struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// FactoryFilterer is an auto generated log filtering Go binding around an Ethereum contract events.
+type FactoryFilterer struct {
+	contract *bind.BoundContract // Generic contract wrapper for the low level calls
+}
+
+// FactorySession is an auto generated Go binding around an Ethereum contract,
+// with pre-set call and transact options.
+type FactorySession struct {
+	Contract     *Factory          // Generic contract binding to set the session for
+	CallOpts     bind.CallOpts     // Call options to use throughout this session
+	TransactOpts bind.TransactOpts // Transaction auth options to use throughout this session
+}
+
+// FactoryCallerSession is an auto generated read-only Go binding around an Ethereum contract,
+// with pre-set call options.
+type FactoryCallerSession struct {
+	Contract *FactoryCaller // Generic contract caller binding to set the session for
+	CallOpts bind.CallOpts  // Call options to use throughout this session
+}
+
+// FactoryTransactorSession is an auto generated write-only Go binding around an Ethereum contract,
+// with pre-set transact options.
+type FactoryTransactorSession struct {
+	Contract     *FactoryTransactor // Generic contract transactor binding to set the session for
+	TransactOpts bind.TransactOpts  // Transaction auth options to use throughout this session
+}
+
+// FactoryRaw is an auto generated low-level Go binding around an Ethereum contract.
+type FactoryRaw struct {
+	Contract *Factory // Generic contract binding to access the raw methods on
+}
+
+// FactoryCallerRaw is an auto generated low-level read-only Go binding around an Ethereum contract.
+type FactoryCallerRaw struct {
+	Contract *FactoryCaller // Generic read-only contract binding to access the raw methods on
+}
+
+// FactoryTransactorRaw is an auto generated low-level write-only Go binding around an Ethereum contract.
+type FactoryTransactorRaw struct {
+	Contract *FactoryTransactor // Generic write-only contract binding to access the raw methods on
+}
+
+// NewFactory creates a new instance of Factory, bound to a specific deployed contract.
+func NewFactory(address common.Address, backend bind.ContractBackend) (*Factory, error) {
+	contract, err := bindFactory(address, backend, backend, backend)
+	if err != nil {
+		return nil, err
+	}
+	return &Factory{FactoryCaller: FactoryCaller{contract: contract}, FactoryTransactor: FactoryTransactor{contract: contract}, FactoryFilterer: FactoryFilterer{contract: contract}}, nil
+}
+
+// NewFactoryCaller creates a new read-only instance of Factory, bound to a specific deployed contract.
+func NewFactoryCaller(address common.Address, caller bind.ContractCaller) (*FactoryCaller, error) {
+	contract, err := bindFactory(address, caller, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &FactoryCaller{contract: contract}, nil
+}
+
+// NewFactoryTransactor creates a new write-only instance of Factory, bound to a specific deployed contract.
+func NewFactoryTransactor(address common.Address, transactor bind.ContractTransactor) (*FactoryTransactor, error) {
+	contract, err := bindFactory(address, nil, transactor, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &FactoryTransactor{contract: contract}, nil
+}
+
+// NewFactoryFilterer creates a new log filterer instance of Factory, bound to a specific deployed contract.
+func NewFactoryFilterer(address common.Address, filterer bind.ContractFilterer) (*FactoryFilterer, error) {
+	contract, err := bindFactory(address, nil, nil, filterer)
+	if err != nil {
+		return nil, err
+	}
+	return &FactoryFilterer{contract: contract}, nil
+}
+
+// bindFactory binds a generic wrapper to an already deployed contract.
+func bindFactory(address common.Address, caller bind.ContractCaller, transactor bind.ContractTransactor, filterer bind.ContractFilterer) (*bind.BoundContract, error) {
+	parsed, err := FactoryMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	return bind.NewBoundContract(address, *parsed, caller, transactor, filterer), nil
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_Factory *FactoryRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _Factory.Contract.FactoryCaller.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_Factory *FactoryRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _Factory.Contract.FactoryTransactor.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_Factory *FactoryRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _Factory.Contract.FactoryTransactor.contract.Transact(opts, method, params...)
+}
+
+// Call invokes the (constant) contract method with params as input values and
+// sets the output to result. The result type might be a single field for simple
+// returns, a slice of interfaces for anonymous returns and a struct for named
+// returns.
+func (_Factory *FactoryCallerRaw) Call(opts *bind.CallOpts, result *[]interface{}, method string, params ...interface{}) error {
+	return _Factory.Contract.contract.Call(opts, result, method, params...)
+}
+
+// Transfer initiates a plain transaction to move funds to the contract, calling
+// its default method if one is available.
+func (_Factory *FactoryTransactorRaw) Transfer(opts *bind.TransactOpts) (*types.Transaction, error) {
+	return _Factory.Contract.contract.Transfer(opts)
+}
+
+// Transact invokes the (paid) contract method with params as input values.
+func (_Factory *FactoryTransactorRaw) Transact(opts *bind.TransactOpts, method string, params ...interface{}) (*types.Transaction, error) {
+	return _Factory.Contract.contract.Transact(opts, method, params...)
+}
+
+// Fallback is a paid mutator transaction binding the contract fallback function.
+//
+// Solidity: fallback() payable returns()
+func (_Factory *FactoryTransactor) Fallback(opts *bind.TransactOpts, calldata []byte) (*types.Transaction, error) {
+	return _Factory.contract.RawTransact(opts, calldata)
+}
+
+// Fallback is a paid mutator transaction binding the contract fallback function.
+//
+// Solidity: fallback() payable returns()
+func (_Factory *FactorySession) Fallback(calldata []byte) (*types.Transaction, error) {
+	return _Factory.Contract.Fallback(&_Factory.TransactOpts, calldata)
+}
+
+// Fallback is a paid mutator transaction binding the contract fallback function.
+//
+// Solidity: fallback() payable returns()
+func (_Factory *FactoryTransactorSession) Fallback(calldata []byte) (*types.Transaction, error) {
+	return _Factory.Contract.Fallback(&_Factory.TransactOpts, calldata)
+}
@@ -0,0 +1,140 @@
+package deployer
+
+import (
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"yunlabs.com/goethereumbook/ethaddr"
+)
+
+// ParseArgs converts command line values to the Go types abi.Arguments.Pack
+// expects for inputs.
+func ParseArgs(inputs abi.Arguments, values []string) ([]interface{}, error) {
+	if len(values) != len(inputs) {
+		return nil, fmt.Errorf("want %d arguments, got %d", len(inputs), len(values))
+	}
+	args := make([]interface{}, len(values))
+	for i, input := range inputs {
+		v, err := ParseValue(input.Type, values[i])
+		if err != nil {
+			return nil, fmt.Errorf("argument %d (%s %s): %v", i, input.Type, input.Name, err)
+		}
+		args[i] = v
+	}
+	return args, nil
+}
+
+// ParseValue converts s to a value of type t. Numbers may be decimal or 0x
+// hex, bytes are 0x hex, arrays are written as [a,b,c].
+func ParseValue(t abi.Type, s string) (interface{}, error) {
+	switch t.T {
+	case abi.AddressTy:
+		return ethaddr.Parse(s)
+	case abi.BoolTy:
+		return strconv.ParseBool(s)
+	case abi.StringTy:
+		return s, nil
+	case abi.BytesTy:
+		return hexutil.Decode(s)
+	case abi.FixedBytesTy:
+		b, err := hexutil.Decode(s)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > t.Size {
+			return nil, fmt.Errorf("longer than %d bytes", t.Size)
+		}
+		v := reflect.New(t.GetType()).Elem()
+		reflect.Copy(v, reflect.ValueOf(b))
+		return v.Interface(), nil
+	case abi.IntTy, abi.UintTy:
+		return parseInt(t, s)
+	case abi.SliceTy, abi.ArrayTy:
+		items := splitList(s)
+		if items == nil {
+			return nil, fmt.Errorf("want [a,b,...], got %q", s)
+		}
+		if t.T == abi.ArrayTy && len(items) != t.Size {
+			return nil, fmt.Errorf("want %d elements, got %d", t.Size, len(items))
+		}
+		var v reflect.Value
+		if t.T == abi.ArrayTy {
+			v = reflect.New(t.GetType()).Elem()
+		} else {
+			v = reflect.MakeSlice(t.GetType(), len(items), len(items))
+		}
+		for i, item := range items {
+			elem, err := ParseValue(*t.Elem, item)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %v", i, err)
+			}
+			v.Index(i).Set(reflect.ValueOf(elem))
+		}
+		return v.Interface(), nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// parseInt 按 abi 对应的 Go 类型返回：不超过 64 位的用 int8..uint64，更大的用 *big.Int。
+// 不接受单位：数量、小数位数、初始供应量这些参数都是整数，合约自己决定要不要乘 10^decimals
+func parseInt(t abi.Type, s string) (interface{}, error) {
+	n, ok := new(big.Int).SetString(s, 0)
+	if !ok {
+		return nil, fmt.Errorf("invalid number %q", s)
+	}
+	if t.T == abi.UintTy && n.Sign() < 0 {
+		return nil, fmt.Errorf("negative value for %s", t)
+	}
+	bits := n.BitLen()
+	if t.T == abi.IntTy {
+		bits++ // 符号位
+	}
+	if bits > t.Size {
+		return nil, fmt.Errorf("%s overflows %s", s, t)
+	}
+	typ := t.GetType()
+	if typ == reflect.TypeOf(&big.Int{}) {
+		return n, nil
+	}
+	v := reflect.New(typ).Elem()
+	if t.T == abi.IntTy {
+		v.SetInt(n.Int64())
+	} else {
+		v.SetUint(n.Uint64())
+	}
+	return v.Interface(), nil
+}
+
+// splitList 拆分 [a,b,c]，支持嵌套的方括号
+func splitList(s string) []string {
+	s = strings.TrimSpace(s)
+	if !strings.HasPrefix(s, "[") || !strings.HasSuffix(s, "]") {
+		return nil
+	}
+	s = s[1 : len(s)-1]
+	items := []string{}
+	if strings.TrimSpace(s) == "" {
+		return items
+	}
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(items, strings.TrimSpace(s[start:]))
+}
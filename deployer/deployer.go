@@ -0,0 +1,88 @@
+// Package deployer 从 contracts/build 中的 solc 产物(<Name>.bin、<Name>.abi)构造部署用的 init code，
+// 并预先计算 CREATE 和 CREATE2 部署出来的合约地址。
+package deployer
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Artifact is a compiled contract.
+type Artifact struct {
+	Name string
+	ABI  *abi.ABI
+	Bin  []byte
+}
+
+// LoadArtifact reads <name>.bin and <name>.abi from dir.
+func LoadArtifact(dir, name string) (*Artifact, error) {
+	bin, err := os.ReadFile(filepath.Join(dir, name+".bin"))
+	if err != nil {
+		return nil, err
+	}
+	code, err := hexutil.Decode("0x" + strings.TrimPrefix(strings.TrimSpace(string(bin)), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("%s.bin: %v", name, err)
+	}
+	f, err := os.Open(filepath.Join(dir, name+".abi"))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	parsed, err := abi.JSON(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s.abi: %v", name, err)
+	}
+	return &Artifact{Name: name, ABI: &parsed, Bin: code}, nil
+}
+
+// InitCode returns the creation bytecode followed by the ABI-encoded
+// constructor arguments given as text.
+func (a *Artifact) InitCode(args []string) ([]byte, error) {
+	values, err := ParseArgs(a.ABI.Constructor.Inputs, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s constructor: %v", a.Name, err)
+	}
+	packed, err := a.ABI.Pack("", values...)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, a.Bin...), packed...), nil
+}
+
+// ParseSalt parses a CREATE2 salt given as 0x hex (left padded to 32 bytes)
+// or as a decimal number.
+func ParseSalt(s string) ([32]byte, error) {
+	var salt [32]byte
+	if strings.HasPrefix(s, "0x") {
+		b, err := hexutil.Decode(s)
+		if err != nil {
+			return salt, fmt.Errorf("invalid salt %q: %v", s, err)
+		}
+		if len(b) > 32 {
+			return salt, fmt.Errorf("salt longer than 32 bytes")
+		}
+		copy(salt[32-len(b):], b)
+		return salt, nil
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok || n.Sign() < 0 || n.BitLen() > 256 {
+		return salt, fmt.Errorf("invalid salt %q", s)
+	}
+	n.FillBytes(salt[:])
+	return salt, nil
+}
+
+// Create2Address returns the address deployer creates with CREATE2 for salt
+// and initCode.
+func Create2Address(deployer common.Address, salt [32]byte, initCode []byte) common.Address {
+	return crypto.CreateAddress2(deployer, salt, crypto.Keccak256(initCode))
+}
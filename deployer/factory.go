@@ -0,0 +1,179 @@
+package deployer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"yunlabs.com/goethereumbook/contracts/factory"
+)
+
+// 确定性部署代理(deterministic-deployment-proxy)。它由一笔没有私钥的预签名交易部署：
+// 签名 r = s = 0x2222...，v = 27，不带链 ID，所以任何链上都能重放，
+// 恢复出来的发送者和 nonce 0 都一样，代理合约因此在每条链上都是同一个地址。
+//
+// 运行时代码就是 contracts/build/Factory.bin 中 0xfe 之后的部分，调用数据为 salt(32 字节) ++ init code：
+//
+//	calldatacopy(0, 32, calldatasize - 32)
+//	addr := create2(callvalue, 0, calldatasize - 32, calldataload(0))
+//	if addr == 0 { revert }
+//	return addr (20 字节)
+var (
+	ProxyAddress = common.HexToAddress("0x4e59b44847b379578588920cA78FbF26c0B4956C")
+	ProxySigner  = common.HexToAddress("0x3fAB184622Dc19b6109349B94811493BF2a45362")
+)
+
+const proxyDeployment = "0xf8a58085174876e800830186a08080b853604580600e600039806000f350fe7fffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffe03601600081602082378035828234f58015156039578182fd5b8082525050506014600cf31ba02222222222222222222222222222222222222222222222222222222222222222a02222222222222222222222222222222222222222222222222222222222222222"
+
+// ErrUnprotectedRejected is returned when the node refuses the pre-signed
+// proxy deployment because it has no chain ID (geth needs
+// --rpc.allow-unprotected-txs).
+var ErrUnprotectedRejected = errors.New("node rejects transactions without replay protection")
+
+// Backend is what deployments need from the node, ethclient.Client
+// satisfies it.
+type Backend interface {
+	bind.ContractBackend
+	bind.DeployBackend
+	BalanceAt(ctx context.Context, account common.Address, blockNumber *big.Int) (*big.Int, error)
+}
+
+// ProxyDeployment returns the pre-signed transaction that creates the proxy.
+func ProxyDeployment() *types.Transaction {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(hexutil.MustDecode(proxyDeployment)); err != nil {
+		panic(err)
+	}
+	return tx
+}
+
+// DeployProxy deploys the deterministic deployment proxy unless it already
+// exists. The keyless signer is funded from opts with exactly the gas the
+// pre-signed transaction needs.
+func DeployProxy(ctx context.Context, backend Backend, opts *bind.TransactOpts) error {
+	code, err := backend.CodeAt(ctx, ProxyAddress, nil)
+	if err != nil {
+		return err
+	}
+	if len(code) > 0 {
+		return nil
+	}
+
+	// 先直接发送：节点不接受无链 ID 交易时立即返回，不会白白给签名账户转账
+	tx := ProxyDeployment()
+	err = sendProxyDeployment(ctx, backend, tx)
+	if err != nil && strings.Contains(err.Error(), "insufficient funds") {
+		if err := fundProxySigner(ctx, backend, opts, tx); err != nil {
+			return fmt.Errorf("funding proxy signer: %v", err)
+		}
+		err = sendProxyDeployment(ctx, backend, tx)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = waitSuccess(ctx, backend, tx)
+	return err
+}
+
+func sendProxyDeployment(ctx context.Context, backend Backend, tx *types.Transaction) error {
+	err := backend.SendTransaction(ctx, tx)
+	if err != nil && (strings.Contains(err.Error(), "replay-protected") || strings.Contains(err.Error(), "EIP-155")) {
+		return fmt.Errorf("%w: %v", ErrUnprotectedRejected, err)
+	}
+	return err
+}
+
+// fundProxySigner 给签名账户转入正好够付 gas 的金额
+func fundProxySigner(ctx context.Context, backend Backend, opts *bind.TransactOpts, tx *types.Transaction) error {
+	cost := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(tx.Gas()))
+	balance, err := backend.BalanceAt(ctx, ProxySigner, nil)
+	if err != nil {
+		return err
+	}
+	if balance.Cmp(cost) >= 0 {
+		return nil
+	}
+	fund := *opts
+	fund.Value = new(big.Int).Sub(cost, balance)
+	fund.GasLimit = 21000 // 普通转账，不让 bind 去检查合约代码
+	ftx, err := bind.NewBoundContract(ProxySigner, abi.ABI{}, nil, backend, nil).Transfer(&fund)
+	if err != nil {
+		return err
+	}
+	_, err = waitSuccess(ctx, backend, ftx)
+	return err
+}
+
+// Create2 deploys initCode through the CREATE2 factory with salt. When the
+// predicted address already has code nothing is sent and the returned
+// transaction is nil, so re-running a deployment is harmless.
+//
+// The constructor runs with msg.sender set to the factory, not the account
+// in opts: a token minting its supply to msg.sender, like contracts/ERC20.sol,
+// credits the factory and the tokens can never be moved. Deploy such tokens
+// through DeployForwarder's factory instead, see CreditsSender.
+func Create2(ctx context.Context, backend Backend, opts *bind.TransactOpts, factoryAddr common.Address, salt [32]byte, initCode []byte) (common.Address, *types.Transaction, error) {
+	addr := Create2Address(factoryAddr, salt, initCode)
+	code, err := backend.CodeAt(ctx, addr, nil)
+	if err != nil {
+		return addr, nil, err
+	}
+	if len(code) > 0 {
+		return addr, nil, nil
+	}
+	code, err = backend.CodeAt(ctx, factoryAddr, nil)
+	if err != nil {
+		return addr, nil, err
+	}
+	if len(code) == 0 {
+		return addr, nil, fmt.Errorf("no factory contract at %s", factoryAddr.Hex())
+	}
+
+	f, err := factory.NewFactoryTransactor(factoryAddr, backend)
+	if err != nil {
+		return addr, nil, err
+	}
+	tx, err := f.Fallback(opts, append(salt[:], initCode...))
+	if err != nil {
+		return addr, nil, err
+	}
+	if _, err := waitSuccess(ctx, backend, tx); err != nil {
+		return addr, tx, err
+	}
+	code, err = backend.CodeAt(ctx, addr, nil)
+	if err != nil {
+		return addr, tx, err
+	}
+	if len(code) == 0 {
+		return addr, tx, fmt.Errorf("no code at %s after deployment", addr.Hex())
+	}
+	return addr, tx, nil
+}
+
+func waitSuccess(ctx context.Context, backend bind.DeployBackend, tx *types.Transaction) (*types.Receipt, error) {
+	receipt, err := bind.WaitMined(ctx, backend, tx)
+	if err != nil {
+		return nil, err
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		return receipt, fmt.Errorf("transaction %s failed", tx.Hash().Hex())
+	}
+	return receipt, nil
+}
+
+// CreditsSender reports whether the contract looks like it credits its
+// deployer: it has a balanceOf or owner method. Deployed through the factory
+// such a contract gives the balance or ownership to the factory.
+func CreditsSender(contract *abi.ABI) bool {
+	_, balances := contract.Methods["balanceOf"]
+	_, owner := contract.Methods["owner"]
+	return balances || owner
+}
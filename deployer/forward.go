@@ -0,0 +1,44 @@
+package deployer
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// 转发工厂：和确定性部署代理一样用 salt(32 字节) ++ init code 调用，CREATE2 部署之后
+// 把新合约记在工厂名下的代币全部转给调用者，ERC20 这种在构造函数里给 msg.sender 铸币的合约
+// 也能部署到固定地址而不丢掉初始供应量。运行时代码：
+//
+//	calldatacopy(0, 32, calldatasize - 32)
+//	addr := create2(callvalue, 0, calldatasize - 32, calldataload(0))
+//	if addr == 0 { revert }
+//	if !staticcall(addr, balanceOf(address)) { revert }
+//	if !call(addr, transfer(caller, balance)) { revert }
+//	return addr (20 字节)
+//
+// 目标不是代币时 balanceOf 调用失败，整个部署回滚
+const forwarderInitCode = "0x606f80600b6000396000f36020360380602060003760003590600034f580601a57600080fd5b6370a0823160e01b600052306004526020600060246000845afa603c57600080fd5b60005163a9059cbb60e01b6000523360045260245260206000604460006000855af1606657600080fd5b6000526014600cf3"
+
+// ForwarderInitCode returns the init code of the forwarding factory.
+func ForwarderInitCode() []byte {
+	return hexutil.MustDecode(forwarderInitCode)
+}
+
+// ForwarderAddress returns where DeployForwarder puts the forwarding
+// factory: CREATE2 through proxy with a zero salt, so the address is the
+// same on every chain that has the proxy.
+func ForwarderAddress(proxy common.Address) common.Address {
+	return Create2Address(proxy, [32]byte{}, ForwarderInitCode())
+}
+
+// DeployForwarder deploys the forwarding factory through proxy unless it
+// already exists. Deployments through the forwarder work like Create2, and
+// the token balance the constructor credits to msg.sender ends up with the
+// account in opts instead of the factory.
+func DeployForwarder(ctx context.Context, backend Backend, opts *bind.TransactOpts, proxy common.Address) (common.Address, *types.Transaction, error) {
+	return Create2(ctx, backend, opts, proxy, [32]byte{}, ForwarderInitCode())
+}
@@ -0,0 +1,75 @@
+// Package ethaddr 严格解析以太坊地址。common.HexToAddress 会把长度不对的输入截断或补零，
+// 拼错的地址也会被悄悄接受；这里要求 0x 加 40 位十六进制，大小写混合时必须符合 EIP-55 校验和。
+package ethaddr
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	ErrPrefix   = errors.New("address must start with 0x")
+	ErrLength   = errors.New("address must be 40 hex characters")
+	ErrHex      = errors.New("address contains non-hex characters")
+	ErrChecksum = errors.New("address has an invalid EIP-55 checksum")
+)
+
+// Checksum tells how the letter case of an address was checked.
+type Checksum int
+
+const (
+	ChecksumNone    Checksum = iota // 全小写或全大写，没有校验和可查
+	ChecksumValid                   // 大小写混合且符合 EIP-55
+	ChecksumInvalid                 // 大小写混合但不符合 EIP-55
+)
+
+func (c Checksum) String() string {
+	switch c {
+	case ChecksumValid:
+		return "ok"
+	case ChecksumInvalid:
+		return "invalid"
+	}
+	return "none"
+}
+
+// Check validates the format of s and reports its checksum state. The error
+// is nil for well-formed addresses even when the checksum is invalid.
+func Check(s string) (common.Address, Checksum, error) {
+	if !strings.HasPrefix(s, "0x") && !strings.HasPrefix(s, "0X") {
+		return common.Address{}, ChecksumNone, ErrPrefix
+	}
+	hex := s[2:]
+	if len(hex) != 2*common.AddressLength {
+		return common.Address{}, ChecksumNone, fmt.Errorf("%w, got %d", ErrLength, len(hex))
+	}
+	for _, c := range hex {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return common.Address{}, ChecksumNone, ErrHex
+		}
+	}
+	addr := common.HexToAddress(s)
+	if hex == strings.ToLower(hex) || hex == strings.ToUpper(hex) {
+		return addr, ChecksumNone, nil
+	}
+	if addr.Hex()[2:] != hex {
+		return addr, ChecksumInvalid, nil
+	}
+	return addr, ChecksumValid, nil
+}
+
+// Parse parses s strictly: wrong lengths, non-hex characters and mixed-case
+// input with a wrong EIP-55 checksum are rejected.
+func Parse(s string) (common.Address, error) {
+	addr, checksum, err := Check(s)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("%q: %w", s, err)
+	}
+	if checksum == ChecksumInvalid {
+		return common.Address{}, fmt.Errorf("%q: %w", s, ErrChecksum)
+	}
+	return addr, nil
+}
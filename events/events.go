@@ -0,0 +1,146 @@
+// Package events 按区块范围分段查询事件日志，节点返回结果太多时自动缩小分段，
+// 并按 topic0 用已知的 ABI 解码日志。
+package events
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"yunlabs.com/goethereumbook/output"
+	"yunlabs.com/goethereumbook/registry"
+)
+
+// DefaultChunk is the number of blocks of one FilterLogs query when
+// Fetcher.Chunk is not set.
+const DefaultChunk = 2000
+
+// growAfter 是缩小分段后连续成功多少次再放大，避免在节点的上限附近来回试
+const growAfter = 4
+
+// ErrStop can be returned by the callback of Fetch to end the query early
+// without an error.
+var ErrStop = errors.New("stop fetching logs")
+
+// Filterer is the part of ethclient.Client the fetcher needs.
+type Filterer interface {
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+}
+
+// Fetcher queries logs over a block range in chunks. When the node refuses
+// a chunk because it has too many results the chunk is halved and retried,
+// after a successful query it grows back up to Chunk.
+type Fetcher struct {
+	Client Filterer
+	Chunk  uint64
+	// Progress is called after each chunk with the blocks done so far.
+	Progress func(done, total uint64)
+}
+
+// Fetch calls fn with the logs of every chunk from from to to, in order.
+// The block range of q is ignored.
+func (f *Fetcher) Fetch(ctx context.Context, q ethereum.FilterQuery, from, to uint64, fn func([]types.Log) error) error {
+	max := f.Chunk
+	if max == 0 {
+		max = DefaultChunk
+	}
+	chunk, ok := max, 0
+	for start := from; start <= to; {
+		end := to
+		if to-start >= chunk {
+			end = start + chunk - 1
+		}
+		q.FromBlock, q.ToBlock = new(big.Int).SetUint64(start), new(big.Int).SetUint64(end)
+		logs, err := f.Client.FilterLogs(ctx, q)
+		if err != nil {
+			if TooManyResults(err) && chunk > 1 {
+				chunk, ok = chunk/2, 0
+				continue
+			}
+			return err
+		}
+		if err := fn(logs); err != nil {
+			if err == ErrStop {
+				return nil
+			}
+			return err
+		}
+		if f.Progress != nil {
+			f.Progress(end-from+1, to-from+1)
+		}
+		if end == to {
+			break
+		}
+		start = end + 1
+		if ok++; ok >= growAfter && chunk < max {
+			chunk, ok = min(chunk*2, max), 0
+		}
+	}
+	return nil
+}
+
+// tooMany 是各家节点和服务商限制 eth_getLogs 结果数量或范围时的错误信息片段
+var tooMany = []string{
+	"too many results",                 // 通用
+	"query returned more than",         // Infura
+	"log response size exceeded",       // Alchemy
+	"block range is too large",         // 多数服务商限制范围
+	"exceed maximum block range",       // BSC 等
+	"response size should not greater", // 部分 geth 分支
+	"limit exceeded",
+	"query timeout",
+}
+
+// TooManyResults reports whether err is a node refusing a log query because
+// the range or the result is too large, which a smaller range may fix.
+func TooManyResults(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, s := range tooMany {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Decode describes l, decoded against reg when its topic0 is known. The
+// decoded log is nil for unknown events.
+func Decode(reg *registry.Registry, l *types.Log) (output.Event, *registry.Log) {
+	ev := output.NewEvent(l)
+	decoded, err := reg.DecodeLog(l)
+	if err != nil {
+		return ev, nil
+	}
+	// 签名里带上参数名和 indexed，如 Transfer(address indexed from, address indexed to, uint256 value)
+	ev.Name, ev.Contract = decoded.Event.Name, decoded.Contract
+	ev.Signature = strings.TrimPrefix(decoded.Event.String(), "event ")
+	ev.Args = decoded.Map()
+	for name, v := range ev.Args {
+		ev.Args[name] = plain(v)
+	}
+	return ev, decoded
+}
+
+// plain 把 bytes32 等定长字节数组换成十六进制，JSON 里不会变成数字数组
+func plain(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Array && rv.Type().Elem().Kind() == reflect.Uint8 {
+		b := make([]byte, rv.Len())
+		reflect.Copy(reflect.ValueOf(b), rv)
+		return hexutil.Bytes(b)
+	}
+	return v
+}
+
+func min(a, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,122 @@
+package explore
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind/backends"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+
+	"yunlabs.com/goethereumbook/contracts/multicall"
+)
+
+// Demo is an in-memory simulated chain with Multicall3 and a few funded
+// accounts sending each other ETH, for trying the explorer without a node.
+//
+// The simulated backend of go-ethereum 1.12 runs ethash rules without
+// Shanghai, so the bundled ERC20, compiled with PUSH0, cannot be deployed
+// on it; Multicall3 is compiled with an older solc and works.
+type Demo struct {
+	*backends.SimulatedBackend
+
+	Accounts  []common.Address
+	Multicall common.Address
+
+	keys  []*ecdsa.PrivateKey
+	mc    *multicall.Multicall3
+	round int
+}
+
+// NewDemo creates the chain and deploys Multicall3 from the first account.
+func NewDemo(accounts int) (*Demo, error) {
+	d := &Demo{}
+	alloc := make(core.GenesisAlloc)
+	fund := new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+	for i := 0; i < accounts; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		addr := crypto.PubkeyToAddress(key.PublicKey)
+		d.keys, d.Accounts = append(d.keys, key), append(d.Accounts, addr)
+		alloc[addr] = core.GenesisAccount{Balance: fund}
+	}
+	d.SimulatedBackend = backends.NewSimulatedBackend(alloc, 30_000_000)
+
+	// 模拟链的创世区块时间是 0，先出一个 30 天前的空区块(AdjustTime 只对空区块有效)。
+	// 之后每个区块固定比上一个晚 10 秒，比实际出块快，时间超过现在的区块不会被接受，所以要从过去开始
+	d.AdjustTime(time.Duration(time.Now().Add(-30*24*time.Hour).Unix()-10) * time.Second)
+	d.Commit()
+
+	var err error
+	if d.Multicall, _, d.mc, err = multicall.DeployMulticall3(d.opts(0, nil), d); err != nil {
+		return nil, err
+	}
+	d.Commit()
+	return d, nil
+}
+
+// opts 返回第 i 个账户的交易参数，模拟链的链 ID 是 1337
+func (d *Demo) opts(i int, value *big.Int) *bind.TransactOpts {
+	opts, _ := bind.NewKeyedTransactorWithChainID(d.keys[i], params.AllEthashProtocolChanges.ChainID)
+	opts.Value = value
+	return opts
+}
+
+// Step sends a few transactions and mines them into a block: an ETH
+// transfer, an aggregate3Value that moves ETH internally and sometimes a
+// reverting call.
+func (d *Demo) Step() error {
+	n := len(d.Accounts)
+	from, to := d.round%n, (d.round+1)%n
+	d.round++
+
+	// 没有 ABI 的 BoundContract.Transfer 就是一笔普通的 ETH 转账，收款方不是合约，要指定 gas 限额跳过估算
+	wei := new(big.Int).Mul(big.NewInt(int64(d.round%5+1)), big.NewInt(params.GWei*1e6))
+	opts := d.opts(from, wei)
+	opts.GasLimit = params.TxGas
+	if _, err := bind.NewBoundContract(d.Accounts[to], abi.ABI{}, nil, d, nil).Transfer(opts); err != nil {
+		return err
+	}
+	if d.round%2 == 0 {
+		value := big.NewInt(params.GWei)
+		if _, err := d.mc.Aggregate3Value(d.opts(from, value), []multicall.Multicall3Call3Value{
+			{Target: d.Accounts[to], AllowFailure: false, Value: value},
+		}); err != nil {
+			return err
+		}
+	}
+	if d.round%5 == 0 {
+		// 调用不存在的方法，Multicall3 没有 fallback，交易会失败；同样要跳过估算
+		opts := d.opts(from, nil)
+		opts.GasLimit = 50_000
+		if _, err := bind.NewBoundContract(d.Multicall, abi.ABI{}, nil, d, nil).RawTransact(opts, []byte{1, 2, 3, 4}); err != nil {
+			return err
+		}
+	}
+	d.Commit()
+	return nil
+}
+
+// Run calls Step every interval until ctx is done.
+func (d *Demo) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := d.Step(); err != nil {
+				return err
+			}
+		}
+	}
+}
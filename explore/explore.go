@@ -0,0 +1,320 @@
+// Package explore 是终端里的区块浏览器：最新区块实时滚动显示，回车逐层进入区块、交易、收据和日志，
+// 已知 ABI 的 calldata 和事件会被解码；地址视图显示余额、nonce、代码和交易记录。
+// 数据只通过 Backend 读取，ethclient 连接的任意节点和内存中的模拟链(Demo)都可以使用。
+package explore
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"yunlabs.com/goethereumbook/contracts/token"
+	"yunlabs.com/goethereumbook/ethaddr"
+	"yunlabs.com/goethereumbook/history"
+	"yunlabs.com/goethereumbook/registry"
+	"yunlabs.com/goethereumbook/units"
+)
+
+// Backend is what the explorer reads the chain through. Both
+// ethclient.Client and the simulated backend implement it.
+type Backend interface {
+	history.Client
+	bind.ContractCaller
+	BalanceAt(ctx context.Context, account common.Address, number *big.Int) (*big.Int, error)
+	NonceAt(ctx context.Context, account common.Address, number *big.Int) (uint64, error)
+}
+
+// Config configures the explorer. Only Backend is required.
+type Config struct {
+	Backend  Backend
+	Registry *registry.Registry
+	// History lists the transactions of an address. When nil the last
+	// HistoryBlocks blocks are scanned through Backend.
+	History       history.Source
+	HistoryBlocks uint64
+	// Label returns the address book label of an address, if any.
+	Label func(common.Address) string
+	// Poll is how often the latest block is checked, Backlog how many
+	// recent blocks are shown at start.
+	Poll    time.Duration
+	Backlog uint64
+}
+
+// Explorer is the terminal UI.
+type Explorer struct {
+	cfg Config
+	ctx context.Context
+
+	app    *tview.Application
+	pages  *tview.Pages
+	stack  []tview.Primitive
+	blocks *tview.Table
+	status *tview.TextView
+	search *tview.InputField
+
+	mu     sync.Mutex
+	tokens map[common.Address]units.Unit
+}
+
+const help = "[yellow]Enter[-] open  [yellow]Esc[-] back  [yellow]/[-] block, tx or address  [yellow]q[-] quit"
+
+// maxBlocks 是首页保留的区块行数
+const maxBlocks = 500
+
+// New builds the UI.
+func New(cfg Config) *Explorer {
+	if cfg.Registry == nil {
+		cfg.Registry = registry.Default()
+	}
+	if cfg.Label == nil {
+		cfg.Label = func(common.Address) string { return "" }
+	}
+	if cfg.Poll <= 0 {
+		cfg.Poll = 2 * time.Second
+	}
+	if cfg.Backlog == 0 {
+		cfg.Backlog = 50
+	}
+	if cfg.HistoryBlocks == 0 {
+		cfg.HistoryBlocks = 1000
+	}
+	e := &Explorer{
+		cfg:    cfg,
+		app:    tview.NewApplication(),
+		pages:  tview.NewPages(),
+		status: tview.NewTextView().SetDynamicColors(true),
+		search: tview.NewInputField().SetLabel("/ "),
+		tokens: make(map[common.Address]units.Unit),
+	}
+	e.status.SetText(help)
+	e.search.SetDoneFunc(e.searchDone)
+
+	e.blocks = newTable("latest blocks")
+	e.blocks.SetCell(0, 0, header("NUMBER"))
+	for i, name := range []string{"TIME", "TXS", "GAS USED", "UTIL", "BASE FEE (gwei)", "MINER"} {
+		e.blocks.SetCell(0, i+1, header(name))
+	}
+	e.blocks.SetFixed(1, 0)
+	e.blocks.SetSelectedFunc(func(row, col int) {
+		if ref := e.blocks.GetCell(row, 0).GetReference(); ref != nil {
+			e.open(ref)
+		}
+	})
+	e.push(e.blocks)
+
+	layout := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(e.pages, 0, 1, true).
+		AddItem(e.search, 1, 0, false).
+		AddItem(e.status, 1, 0, false)
+	e.app.SetRoot(layout, true).SetInputCapture(e.keys)
+	return e
+}
+
+// SetScreen draws on screen instead of the terminal, for tests and demos.
+func (e *Explorer) SetScreen(screen tcell.Screen) {
+	e.app.SetScreen(screen)
+}
+
+// Run follows the chain and shows the UI until q is pressed or ctx is done.
+func (e *Explorer) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	e.ctx = ctx
+	go e.follow(ctx)
+	go func() {
+		<-ctx.Done()
+		e.app.Stop()
+	}()
+	return e.app.Run()
+}
+
+func (e *Explorer) keys(ev *tcell.EventKey) *tcell.EventKey {
+	if e.search.HasFocus() {
+		return ev
+	}
+	switch {
+	case ev.Key() == tcell.KeyEscape || ev.Key() == tcell.KeyBackspace || ev.Key() == tcell.KeyBackspace2:
+		e.pop()
+		return nil
+	case ev.Rune() == '/':
+		e.search.SetText("")
+		e.app.SetFocus(e.search)
+		return nil
+	case ev.Rune() == 'q':
+		e.app.Stop()
+		return nil
+	}
+	return ev
+}
+
+// searchDone 按输入的格式跳转：数字是区块号，32 字节是交易哈希，20 字节是地址
+func (e *Explorer) searchDone(key tcell.Key) {
+	text := strings.TrimSpace(e.search.GetText())
+	e.search.SetText("")
+	e.app.SetFocus(e.stack[len(e.stack)-1])
+	if key != tcell.KeyEnter || text == "" {
+		return
+	}
+	if n, err := strconv.ParseUint(text, 10, 64); err == nil {
+		e.open(blockRef(n))
+		return
+	}
+	switch {
+	case len(text) == 66 && strings.HasPrefix(text, "0x"):
+		e.open(common.HexToHash(text))
+	default:
+		// 地址按 EIP-55 严格解析，大小写混写但校验和不对的地址不接受
+		addr, err := ethaddr.Parse(text)
+		if err != nil {
+			e.errorf("not a block number, transaction hash or address: %v", err)
+			return
+		}
+		e.open(addr)
+	}
+}
+
+// blockRef 是表格行指向的区块，和交易哈希(common.Hash)、地址(common.Address)一样可以回车打开
+type blockRef uint64
+
+// open 在后台加载引用的对象，加载完成后压入新的一页
+func (e *Explorer) open(ref interface{}) {
+	var load func() (tview.Primitive, error)
+	switch ref := ref.(type) {
+	case blockRef:
+		load = func() (tview.Primitive, error) { return e.blockView(uint64(ref)) }
+	case common.Hash:
+		load = func() (tview.Primitive, error) { return e.txView(ref) }
+	case common.Address:
+		load = func() (tview.Primitive, error) { return e.addressView(ref) }
+	default:
+		return
+	}
+	e.status.SetText("loading...")
+	go func() {
+		view, err := load()
+		e.app.QueueUpdateDraw(func() {
+			if err != nil {
+				e.errorf("%v", err)
+				return
+			}
+			e.status.SetText(help)
+			e.push(view)
+		})
+	}()
+}
+
+func (e *Explorer) push(view tview.Primitive) {
+	e.stack = append(e.stack, view)
+	name := strconv.Itoa(len(e.stack))
+	e.pages.AddAndSwitchToPage(name, view, true)
+	e.app.SetFocus(view)
+}
+
+func (e *Explorer) pop() {
+	if len(e.stack) == 1 {
+		return
+	}
+	e.pages.RemovePage(strconv.Itoa(len(e.stack)))
+	e.stack = e.stack[:len(e.stack)-1]
+	e.pages.SwitchToPage(strconv.Itoa(len(e.stack)))
+	e.app.SetFocus(e.stack[len(e.stack)-1])
+	e.status.SetText(help)
+}
+
+func (e *Explorer) errorf(format string, args ...interface{}) {
+	e.status.SetText("[red]" + tview.Escape(fmt.Sprintf(format, args...)) + "[-]  " + help)
+}
+
+// follow 轮询最新区块，把新区块插入首页表格的最上面。轮询对 HTTP 节点和模拟链都适用
+func (e *Explorer) follow(ctx context.Context) {
+	var next uint64
+	first := true
+	for {
+		header, err := e.cfg.Backend.HeaderByNumber(ctx, nil)
+		if err != nil {
+			e.app.QueueUpdateDraw(func() { e.errorf("latest block: %v", err) })
+		} else {
+			latest := header.Number.Uint64()
+			if first {
+				if latest+1 > e.cfg.Backlog {
+					next = latest + 1 - e.cfg.Backlog
+				}
+				first = false
+			}
+			for ; next <= latest; next++ {
+				block, err := e.cfg.Backend.BlockByNumber(ctx, new(big.Int).SetUint64(next))
+				if err != nil {
+					number := next
+					e.app.QueueUpdateDraw(func() { e.errorf("block %d: %v", number, err) })
+					break
+				}
+				row := blockRow(block)
+				e.app.QueueUpdateDraw(func() { e.addBlock(row) })
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(e.cfg.Poll):
+		}
+	}
+}
+
+// addBlock 在表头下插入一行，选中的行跟着原来的区块移动，停在第一行时保持看最新的区块
+func (e *Explorer) addBlock(cells []*tview.TableCell) {
+	row, _ := e.blocks.GetSelection()
+	e.blocks.InsertRow(1)
+	for i, c := range cells {
+		e.blocks.SetCell(1, i, c)
+	}
+	if e.blocks.GetRowCount() > maxBlocks+1 {
+		e.blocks.RemoveRow(e.blocks.GetRowCount() - 1)
+	}
+	if row > 1 {
+		e.blocks.Select(row+1, 0)
+	} else {
+		e.blocks.Select(1, 0)
+	}
+}
+
+// unit 返回代币的 symbol 和 decimals，读取失败时显示地址、按 0 位小数
+func (e *Explorer) unit(addr common.Address) units.Unit {
+	e.mu.Lock()
+	u, ok := e.tokens[addr]
+	e.mu.Unlock()
+	if ok {
+		return u
+	}
+	u = units.Unit{Name: addr.Hex()[:10]}
+	if t, err := token.NewTokenCaller(addr, e.cfg.Backend); err == nil {
+		opts := &bind.CallOpts{Context: e.ctx}
+		symbol, err1 := t.Symbol(opts)
+		decimals, err2 := t.Decimals(opts)
+		if err1 == nil && err2 == nil {
+			u = units.Unit{Name: symbol, Decimals: decimals}
+		}
+	}
+	e.mu.Lock()
+	e.tokens[addr] = u
+	e.mu.Unlock()
+	return u
+}
+
+func newTable(title string) *tview.Table {
+	t := tview.NewTable().SetSelectable(true, false)
+	t.SetBorder(true).SetTitle(" " + title + " ")
+	return t
+}
+
+func header(text string) *tview.TableCell {
+	return tview.NewTableCell(text).SetTextColor(tcell.ColorYellow).SetSelectable(false)
+}
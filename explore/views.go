@@ -0,0 +1,337 @@
+package explore
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+
+	"yunlabs.com/goethereumbook/history"
+	"yunlabs.com/goethereumbook/units"
+)
+
+// detail 是两列的表格：左边是字段名，右边是值；值带引用的行可以回车打开
+type detail struct {
+	*tview.Table
+}
+
+func newDetail(e *Explorer, title string) *detail {
+	d := &detail{newTable(title)}
+	d.SetSelectedFunc(func(row, col int) {
+		if ref := d.GetCell(row, 1).GetReference(); ref != nil {
+			e.open(ref)
+		}
+	})
+	return d
+}
+
+func (d *detail) add(key, value string, ref interface{}) {
+	row := d.GetRowCount()
+	d.SetCell(row, 0, tview.NewTableCell(key).SetTextColor(tcell.ColorAqua))
+	cell := tview.NewTableCell(tview.Escape(value)).SetExpansion(1)
+	if ref != nil {
+		cell.SetReference(ref).SetTextColor(tcell.ColorGreen)
+	}
+	d.SetCell(row, 1, cell)
+}
+
+// section 是不可选中的分隔行，返回右边说明的单元格，方便之后更新
+func (d *detail) section(title, note string) *tview.TableCell {
+	row := d.GetRowCount()
+	d.SetCell(row, 0, header(title))
+	cell := header(tview.Escape(note))
+	d.SetCell(row, 1, cell)
+	return cell
+}
+
+func blockRow(block *types.Block) []*tview.TableCell {
+	cells := []*tview.TableCell{
+		tview.NewTableCell(fmt.Sprint(block.NumberU64())).SetReference(blockRef(block.NumberU64())),
+		tview.NewTableCell(blockTime(block.Time())),
+		tview.NewTableCell(fmt.Sprint(len(block.Transactions()))).SetAlign(tview.AlignRight),
+		tview.NewTableCell(fmt.Sprint(block.GasUsed())).SetAlign(tview.AlignRight),
+		tview.NewTableCell(fmt.Sprintf("%.1f%%", utilization(block.GasUsed(), block.GasLimit()))).SetAlign(tview.AlignRight),
+		tview.NewTableCell(gwei(block.BaseFee())).SetAlign(tview.AlignRight),
+		tview.NewTableCell(block.Coinbase().Hex()),
+	}
+	return cells
+}
+
+func (e *Explorer) blockView(number uint64) (tview.Primitive, error) {
+	block, err := e.cfg.Backend.BlockByNumber(e.ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return nil, fmt.Errorf("block %d: %v", number, err)
+	}
+	d := newDetail(e, fmt.Sprintf("block %d", number))
+	d.add("hash", block.Hash().Hex(), nil)
+	if number > 0 {
+		d.add("parent", block.ParentHash().Hex(), blockRef(number-1))
+	}
+	d.add("time", blockTime(block.Time()), nil)
+	d.add("miner", e.address(block.Coinbase()), block.Coinbase())
+	d.add("gas used", fmt.Sprintf("%d / %d (%.1f%%)", block.GasUsed(), block.GasLimit(), utilization(block.GasUsed(), block.GasLimit())), nil)
+	if block.BaseFee() != nil {
+		d.add("base fee", gwei(block.BaseFee())+" gwei", nil)
+	}
+	d.add("difficulty", block.Difficulty().String(), nil)
+
+	d.section("transactions", fmt.Sprint(len(block.Transactions())))
+	for i, tx := range block.Transactions() {
+		from, _ := sender(tx)
+		to := "create"
+		if tx.To() != nil {
+			to = e.address(*tx.To())
+		}
+		line := fmt.Sprintf("%s  %s -> %s  %s", tx.Hash().Hex(), e.address(from), to, units.FormatEther(tx.Value()))
+		if call, err := e.cfg.Registry.DecodeCall(tx.Data()); err == nil {
+			line += "  " + call.Method.Name
+		}
+		d.add(fmt.Sprintf("#%d", i), line, tx.Hash())
+	}
+	d.Select(0, 0)
+	return d, nil
+}
+
+func (e *Explorer) txView(hash common.Hash) (tview.Primitive, error) {
+	ctx := e.ctx
+	tx, pending, err := e.cfg.Backend.TransactionByHash(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("transaction %s: %v", hash.Hex(), err)
+	}
+	from, err := sender(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	d := newDetail(e, "transaction")
+	d.add("hash", hash.Hex(), nil)
+	var receipt *types.Receipt
+	if pending {
+		d.add("status", "pending", nil)
+	} else if receipt, err = e.cfg.Backend.TransactionReceipt(ctx, hash); err != nil {
+		return nil, fmt.Errorf("receipt %s: %v", hash.Hex(), err)
+	}
+	if receipt != nil {
+		status := "success"
+		if receipt.Status == types.ReceiptStatusFailed {
+			status = "failed"
+		}
+		d.add("status", status, nil)
+		d.add("block", fmt.Sprintf("%d (index %d)", receipt.BlockNumber, receipt.TransactionIndex), blockRef(receipt.BlockNumber.Uint64()))
+	}
+	d.add("from", e.address(from), from)
+	switch {
+	case tx.To() != nil:
+		d.add("to", e.address(*tx.To()), *tx.To())
+	case receipt != nil:
+		d.add("created", e.address(receipt.ContractAddress), receipt.ContractAddress)
+	}
+	d.add("value", units.FormatEther(tx.Value()), nil)
+	d.add("nonce", fmt.Sprint(tx.Nonce()), nil)
+	d.add("type", txType(tx.Type()), nil)
+	d.add("gas limit", fmt.Sprint(tx.Gas()), nil)
+	if tx.Type() == types.DynamicFeeTxType {
+		d.add("max fee", gwei(tx.GasFeeCap())+" gwei", nil)
+		d.add("max priority fee", gwei(tx.GasTipCap())+" gwei", nil)
+	} else {
+		d.add("gas price", gwei(tx.GasPrice())+" gwei", nil)
+	}
+
+	d.section("input", "")
+	if len(tx.Data()) == 0 {
+		d.add("data", "none", nil)
+	} else {
+		if call, err := e.cfg.Registry.DecodeCall(tx.Data()); err == nil {
+			d.add("call", call.String(), nil)
+		} else {
+			d.add("selector", hexutil.Encode(tx.Data()[:min(4, len(tx.Data()))]), nil)
+		}
+		d.add("data", fmt.Sprintf("%d bytes %s", len(tx.Data()), shorten(hexutil.Encode(tx.Data()), 130)), nil)
+	}
+
+	if receipt != nil {
+		d.section("receipt", "")
+		d.add("gas used", fmt.Sprint(receipt.GasUsed), nil)
+		if receipt.EffectiveGasPrice != nil {
+			d.add("effective gas price", gwei(receipt.EffectiveGasPrice)+" gwei", nil)
+			d.add("fee", units.FormatEther(history.Fee(tx, receipt)), nil)
+		}
+		d.add("cumulative gas", fmt.Sprint(receipt.CumulativeGasUsed), nil)
+
+		d.section("logs", fmt.Sprint(len(receipt.Logs)))
+		for _, l := range receipt.Logs {
+			d.add(fmt.Sprintf("log %d", l.Index), e.address(l.Address), l.Address)
+			if event, err := e.cfg.Registry.DecodeLog(l); err == nil {
+				d.add("", event.String(), nil)
+				continue
+			}
+			for i, topic := range l.Topics {
+				d.add(fmt.Sprintf("  topic %d", i), topic.Hex(), nil)
+			}
+			if len(l.Data) > 0 {
+				d.add("  data", shorten(hexutil.Encode(l.Data), 130), nil)
+			}
+		}
+	}
+	d.Select(0, 0)
+	return d, nil
+}
+
+// addressView 先显示余额、nonce 和代码，交易记录在后台查找，找到后追加到表格下面
+func (e *Explorer) addressView(addr common.Address) (tview.Primitive, error) {
+	ctx := e.ctx
+	balance, err := e.cfg.Backend.BalanceAt(ctx, addr, nil)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := e.cfg.Backend.NonceAt(ctx, addr, nil)
+	if err != nil {
+		return nil, err
+	}
+	code, err := e.cfg.Backend.CodeAt(ctx, addr, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	d := newDetail(e, "address")
+	d.add("address", addr.Hex(), nil)
+	if label := e.cfg.Label(addr); label != "" {
+		d.add("label", label, nil)
+	}
+	d.add("balance", units.FormatEther(balance), nil)
+	d.add("nonce", fmt.Sprint(nonce), nil)
+	if len(code) == 0 {
+		d.add("code", "none (externally owned account)", nil)
+	} else {
+		d.add("code", fmt.Sprintf("%d bytes %s", len(code), shorten(hexutil.Encode(code), 66)), nil)
+	}
+
+	source, from, to := e.cfg.History, uint64(0), uint64(math.MaxUint64)
+	scope := "local index"
+	if source == nil {
+		header, err := e.cfg.Backend.HeaderByNumber(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		to = header.Number.Uint64()
+		if to > e.cfg.HistoryBlocks {
+			from = to - e.cfg.HistoryBlocks
+		}
+		source = &history.Scanner{Client: e.cfg.Backend}
+		scope = fmt.Sprintf("blocks %d-%d", from, to)
+	}
+	note := d.section("history", scope+", loading...")
+	d.Select(0, 0)
+
+	go func() {
+		entries, err := e.history(source, addr, from, to)
+		e.app.QueueUpdateDraw(func() {
+			if err != nil {
+				note.SetText(tview.Escape(scope + ": " + err.Error()))
+				return
+			}
+			note.SetText(fmt.Sprintf("%s, %d entries", scope, len(entries)))
+			for _, entry := range entries {
+				arrow := "->"
+				if entry.Direction == "in" {
+					arrow = "<-"
+				}
+				line := fmt.Sprintf("%s %s %s %s %s", entry.Kind, arrow, e.address(entry.Counterparty), entry.Amount, entry.Asset)
+				if entry.Method != "" {
+					line += "  " + entry.Method
+				}
+				if entry.Status == types.ReceiptStatusFailed {
+					line += "  failed"
+				}
+				d.add(fmt.Sprintf("%d", entry.BlockNumber), line, entry.TxHash)
+			}
+		})
+	}()
+	return d, nil
+}
+
+// history 读取地址最近的交易(最多 100 笔)，转成以该地址为视角的流水
+func (e *Explorer) history(source history.Source, addr common.Address, from, to uint64) ([]history.Entry, error) {
+	refs, err := source.Refs(e.ctx, addr, from, to)
+	if err != nil {
+		return nil, err
+	}
+	history.Sort(refs, false)
+	if len(refs) > 100 {
+		refs = refs[:100]
+	}
+	b := &history.Builder{Registry: e.cfg.Registry, Token: e.unit}
+	var entries []history.Entry
+	for _, ref := range refs {
+		tx, err := source.Load(e.ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, b.Entries(addr, tx)...)
+	}
+	return entries, nil
+}
+
+// address 在地址后面加上地址簿中的标签
+func (e *Explorer) address(addr common.Address) string {
+	if label := e.cfg.Label(addr); label != "" {
+		return addr.Hex() + " (" + label + ")"
+	}
+	return addr.Hex()
+}
+
+func sender(tx *types.Transaction) (common.Address, error) {
+	return types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+}
+
+func txType(t uint8) string {
+	switch t {
+	case types.LegacyTxType:
+		return "0 (legacy)"
+	case types.AccessListTxType:
+		return "1 (access list)"
+	case types.DynamicFeeTxType:
+		return "2 (dynamic fee)"
+	case types.BlobTxType:
+		return "3 (blob)"
+	}
+	return fmt.Sprint(t)
+}
+
+func blockTime(t uint64) string {
+	return time.Unix(int64(t), 0).UTC().Format("2006-01-02 15:04:05")
+}
+
+func utilization(used, limit uint64) float64 {
+	if limit == 0 {
+		return 0
+	}
+	return float64(used) / float64(limit) * 100
+}
+
+func gwei(wei *big.Int) string {
+	if wei == nil {
+		return "-"
+	}
+	return units.Format(wei, units.Gwei.Decimals, units.Exact)
+}
+
+func shorten(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
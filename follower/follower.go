@@ -0,0 +1,316 @@
+// Package follower 跟随链头并维护最近一段区块头窗口。
+// 新区块与窗口末尾对不上时按父哈希回溯找到共同祖先，先发出被移除区块的
+// Removed 事件，再按顺序发出新分支的 Added 事件，下游只需按事件增删即可。
+package follower
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ErrReorgTooDeep is returned when a reorg reaches below the tracked window.
+var ErrReorgTooDeep = errors.New("reorg deeper than the header window")
+
+// EventType tells whether a block joined or left the canonical chain.
+type EventType int
+
+const (
+	Added EventType = iota
+	Removed
+)
+
+func (t EventType) String() string {
+	if t == Removed {
+		return "removed"
+	}
+	return "added"
+}
+
+// Event is emitted for every block entering or leaving the canonical chain.
+type Event struct {
+	Type   EventType
+	Header *types.Header
+}
+
+// Handler consumes events. An error stops the follower; the event that
+// failed is not applied to the window, so it is delivered again on restart.
+type Handler func(Event) error
+
+// Config holds the follower settings.
+type Config struct {
+	WSURL        string        // websocket 地址，为空时只用 HTTP 轮询
+	Window       int           // 保留的最近区块头个数，决定能处理的最大重组深度
+	PollInterval time.Duration // HTTP 轮询间隔
+	MaxBackoff   time.Duration // websocket 重连的最大等待时间
+	From         *big.Int      // 窗口为空时从哪个区块开始，nil 表示从最新区块开始
+}
+
+// Follower tracks the canonical chain through client.
+type Follower struct {
+	client  ethereum.ChainReader
+	cfg     Config
+	headers []*types.Header // 按区块号升序、连续的最近区块头
+}
+
+// New creates a follower reading headers from client.
+func New(client ethereum.ChainReader, cfg Config) *Follower {
+	if cfg.Window <= 0 {
+		cfg.Window = 128
+	}
+	if cfg.PollInterval <= 0 {
+		cfg.PollInterval = 2 * time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Minute
+	}
+	return &Follower{client: client, cfg: cfg}
+}
+
+// Seed preloads the window with headers already processed by the consumer,
+// in ascending order, so that a reorg which happened while offline is still
+// detected and reported as Removed events.
+func (f *Follower) Seed(headers []*types.Header) {
+	f.headers = append([]*types.Header{}, headers...)
+	f.trim()
+}
+
+// Tip returns the latest header in the window, or nil.
+func (f *Follower) Tip() *types.Header {
+	if len(f.headers) == 0 {
+		return nil
+	}
+	return f.headers[len(f.headers)-1]
+}
+
+// Run follows the chain until ctx is cancelled or handler fails. New heads
+// come from a websocket subscription; while it is down the follower polls
+// HeaderByNumber over HTTP and retries the websocket with exponential backoff.
+func (f *Follower) Run(ctx context.Context, handler Handler) error {
+	if f.cfg.WSURL == "" {
+		return f.poll(ctx, handler, 0)
+	}
+	backoff := time.Second
+	for {
+		healthy, err := f.subscribe(ctx, handler)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := fatal(err); err != nil {
+			return err
+		}
+		if healthy {
+			backoff = time.Second
+		}
+		log.Printf("websocket: %v, polling over http for %v", err, backoff)
+
+		// 重连之前先用 HTTP 轮询，不漏掉这段时间的区块
+		if err := f.poll(ctx, handler, backoff); err != nil {
+			return err
+		}
+		if backoff *= 2; backoff > f.cfg.MaxBackoff {
+			backoff = f.cfg.MaxBackoff
+		}
+	}
+}
+
+// handlerError 区分下游处理失败和网络错误，前者直接返回，后者触发重连
+type handlerError struct{ err error }
+
+func (e *handlerError) Error() string { return e.err.Error() }
+
+// fatal 返回需要终止跟随的错误：下游处理失败或重组超出窗口；网络错误返回 nil，由调用方重试
+func fatal(err error) error {
+	var herr *handlerError
+	if errors.As(err, &herr) {
+		return herr.err
+	}
+	if errors.Is(err, ErrReorgTooDeep) {
+		return err
+	}
+	return nil
+}
+
+// subscribe 通过 websocket 订阅 newHeads，直到订阅出错。healthy 表示期间收到过区块头
+func (f *Follower) subscribe(ctx context.Context, handler Handler) (healthy bool, err error) {
+	client, err := ethclient.DialContext(ctx, f.cfg.WSURL)
+	if err != nil {
+		return false, err
+	}
+	defer client.Close()
+
+	heads := make(chan *types.Header)
+	sub, err := client.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		return false, err
+	}
+	defer sub.Unsubscribe()
+
+	// 订阅成功后先追上当前链头
+	if err := f.pollOnce(ctx, handler); err != nil {
+		return false, err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return healthy, ctx.Err()
+		case err := <-sub.Err():
+			return healthy, err
+		case head := <-heads:
+			healthy = true
+			if err := f.advance(ctx, head, handler); err != nil {
+				return healthy, err
+			}
+		}
+	}
+}
+
+// poll 每隔 PollInterval 查询一次最新区块头，duration 为 0 时一直轮询
+func (f *Follower) poll(ctx context.Context, handler Handler, duration time.Duration) error {
+	var deadline <-chan time.Time
+	if duration > 0 {
+		deadline = time.After(duration)
+	}
+	ticker := time.NewTicker(f.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := f.pollOnce(ctx, handler); err != nil {
+			if err := fatal(err); err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Println("poll:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (f *Follower) pollOnce(ctx context.Context, handler Handler) error {
+	head, err := f.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return err
+	}
+	return f.advance(ctx, head, handler)
+}
+
+// advance 把窗口推进到 head。中间缺失的区块按区块号逐个补齐，每一步都做重组检查
+func (f *Follower) advance(ctx context.Context, head *types.Header, handler Handler) error {
+	if len(f.headers) == 0 && f.cfg.From != nil {
+		switch f.cfg.From.Cmp(head.Number) {
+		case 1:
+			return nil // 还没到起始区块
+		case -1:
+			first, err := f.client.HeaderByNumber(ctx, f.cfg.From)
+			if err != nil {
+				return err
+			}
+			if err := f.process(ctx, first, handler); err != nil {
+				return err
+			}
+		}
+	}
+	for tip := f.Tip(); tip != nil && tip.Number.Uint64()+1 < head.Number.Uint64(); tip = f.Tip() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		next, err := f.client.HeaderByNumber(ctx, new(big.Int).Add(tip.Number, big.NewInt(1)))
+		if err != nil {
+			return err
+		}
+		if err := f.process(ctx, next, handler); err != nil {
+			return err
+		}
+	}
+	return f.process(ctx, head, handler)
+}
+
+// process 处理单个新区块头：正常延伸时直接追加，否则回溯父哈希找共同祖先
+func (f *Follower) process(ctx context.Context, head *types.Header, handler Handler) error {
+	tip := f.Tip()
+	if tip == nil {
+		return f.add([]*types.Header{head}, handler)
+	}
+	if known := f.get(head.Number.Uint64()); known != nil && known.Hash() == head.Hash() {
+		return nil // 已经处理过
+	}
+	if head.Number.Uint64() < f.headers[0].Number.Uint64() {
+		return nil // 比窗口还旧的区块头，忽略
+	}
+	if head.Number.Uint64() == tip.Number.Uint64()+1 && head.ParentHash == tip.Hash() {
+		return f.add([]*types.Header{head}, handler)
+	}
+
+	// 回溯新分支直到与窗口中的某个区块相连
+	branch := []*types.Header{head}
+	for cur := head; ; {
+		if cur.Number.Sign() == 0 {
+			return ErrReorgTooDeep
+		}
+		parentNumber := cur.Number.Uint64() - 1
+		if parentNumber < f.headers[0].Number.Uint64() {
+			return fmt.Errorf("%w: block %d", ErrReorgTooDeep, head.Number)
+		}
+		if known := f.get(parentNumber); known != nil && known.Hash() == cur.ParentHash {
+			break
+		}
+		parent, err := f.client.HeaderByHash(ctx, cur.ParentHash)
+		if err != nil {
+			return err
+		}
+		branch = append([]*types.Header{parent}, branch...)
+		cur = parent
+	}
+
+	ancestor := branch[0].Number.Uint64() - 1
+	log.Printf("reorg: new head %d %x, common ancestor %d", head.Number, head.Hash(), ancestor)
+	for tip := f.Tip(); tip != nil && tip.Number.Uint64() > ancestor; tip = f.Tip() {
+		if err := handler(Event{Type: Removed, Header: tip}); err != nil {
+			return &handlerError{err}
+		}
+		f.headers = f.headers[:len(f.headers)-1]
+	}
+	return f.add(branch, handler)
+}
+
+func (f *Follower) add(headers []*types.Header, handler Handler) error {
+	for _, header := range headers {
+		if err := handler(Event{Type: Added, Header: header}); err != nil {
+			return &handlerError{err}
+		}
+		f.headers = append(f.headers, header)
+		f.trim()
+	}
+	return nil
+}
+
+func (f *Follower) get(number uint64) *types.Header {
+	if len(f.headers) == 0 || number < f.headers[0].Number.Uint64() {
+		return nil
+	}
+	if idx := number - f.headers[0].Number.Uint64(); idx < uint64(len(f.headers)) {
+		return f.headers[idx]
+	}
+	return nil
+}
+
+func (f *Follower) trim() {
+	if len(f.headers) > f.cfg.Window {
+		f.headers = f.headers[len(f.headers)-f.cfg.Window:]
+	}
+}
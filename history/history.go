@@ -0,0 +1,169 @@
+// Package history 列出一个地址的交易记录：作为发送方、接收方、合约创建者、ERC20 Transfer 的一方，
+// 或内部转账的一方。交易来自本地索引，没有索引时逐块扫描节点。每笔交易拆成若干条以该地址为视角的资金流水，
+// 包括方向、对手方、金额、手续费和状态，方便对账。
+package history
+
+import (
+	"context"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"yunlabs.com/goethereumbook/registry"
+	"yunlabs.com/goethereumbook/trace"
+	"yunlabs.com/goethereumbook/units"
+)
+
+// TransferTopic is the topic of the ERC20 Transfer event,
+// keccak256("Transfer(address,address,uint256)").
+var TransferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// Ref points to a transaction touching the address.
+type Ref struct {
+	Block uint64
+	Index uint
+	Hash  common.Hash
+}
+
+// Sort orders refs by position in the chain, newest first unless asc.
+func Sort(refs []Ref, asc bool) {
+	sort.Slice(refs, func(i, j int) bool {
+		a, b := refs[i], refs[j]
+		if a.Block != b.Block {
+			return (a.Block < b.Block) == asc
+		}
+		return (a.Index < b.Index) == asc
+	})
+}
+
+// Tx is a mined transaction with everything needed to build its entries.
+type Tx struct {
+	Block    uint64
+	Time     uint64
+	Index    uint
+	From     common.Address
+	Tx       *types.Transaction
+	Receipt  *types.Receipt
+	Internal []trace.Transfer
+}
+
+// Source finds and loads the transactions of an address.
+type Source interface {
+	// Refs lists the transactions touching addr in blocks [from, to].
+	Refs(ctx context.Context, addr common.Address, from, to uint64) ([]Ref, error)
+	// Load returns a transaction found by Refs.
+	Load(ctx context.Context, ref Ref) (*Tx, error)
+}
+
+// Entry is one movement of value seen from the address. Kind is tx for the
+// transaction itself, create for a contract creation, internal for ETH
+// moved by a contract and token for an ERC20 Transfer. Fee is only set on
+// the tx or create entry of transactions the address sent.
+type Entry struct {
+	BlockNumber       uint64          `json:"blockNumber"`
+	Time              time.Time       `json:"time"`
+	TxHash            common.Hash     `json:"transactionHash"`
+	Kind              string          `json:"kind"`
+	Direction         string          `json:"direction"`
+	Counterparty      common.Address  `json:"counterparty"`
+	CounterpartyLabel string          `json:"counterpartyLabel,omitempty"`
+	Asset             string          `json:"asset"`
+	Token             *common.Address `json:"token,omitempty"`
+	Value             *big.Int        `json:"value"`
+	Amount            string          `json:"amount"`
+	Fee               *big.Int        `json:"fee,omitempty"`
+	Method            string          `json:"method,omitempty"`
+	Status            uint64          `json:"status"`
+}
+
+// Builder turns transactions into entries.
+type Builder struct {
+	Registry *registry.Registry
+	// Token returns the denomination of an ERC20 token.
+	Token func(token common.Address) units.Unit
+}
+
+// Entries lists the movements of tx seen from addr.
+func (b *Builder) Entries(addr common.Address, tx *Tx) []Entry {
+	base := Entry{
+		BlockNumber: tx.Block,
+		Time:        time.Unix(int64(tx.Time), 0).UTC(),
+		TxHash:      tx.Tx.Hash(),
+		Status:      tx.Receipt.Status,
+	}
+	if call, err := b.Registry.DecodeCall(tx.Tx.Data()); err == nil {
+		base.Method = call.Method.Name
+	}
+	eth := func(e Entry, kind, direction string, counterparty common.Address, value *big.Int) Entry {
+		e.Kind, e.Direction, e.Counterparty = kind, direction, counterparty
+		e.Asset, e.Value = units.ETH.Name, value
+		e.Amount = units.Format(value, units.ETH.Decimals, units.Exact)
+		return e
+	}
+
+	var entries []Entry
+	to, created := tx.Tx.To(), tx.Receipt.ContractAddress
+	switch {
+	case tx.From == addr:
+		e := base
+		e.Fee = Fee(tx.Tx, tx.Receipt)
+		switch {
+		case to == nil:
+			entries = append(entries, eth(e, "create", "out", created, tx.Tx.Value()))
+		case *to == addr:
+			entries = append(entries, eth(e, "tx", "self", addr, tx.Tx.Value()))
+		default:
+			entries = append(entries, eth(e, "tx", "out", *to, tx.Tx.Value()))
+		}
+	case to != nil && *to == addr:
+		entries = append(entries, eth(base, "tx", "in", tx.From, tx.Tx.Value()))
+	case to == nil && created == addr:
+		entries = append(entries, eth(base, "create", "in", tx.From, tx.Tx.Value()))
+	}
+
+	for _, t := range tx.Internal {
+		switch addr {
+		case t.From:
+			entries = append(entries, eth(base, "internal", "out", t.To, t.Value))
+		case t.To:
+			entries = append(entries, eth(base, "internal", "in", t.From, t.Value))
+		}
+	}
+
+	for _, l := range tx.Receipt.Logs {
+		if len(l.Topics) != 3 || l.Topics[0] != TransferTopic || len(l.Data) != 32 {
+			continue
+		}
+		from, to := common.BytesToAddress(l.Topics[1].Bytes()), common.BytesToAddress(l.Topics[2].Bytes())
+		if from != addr && to != addr {
+			continue
+		}
+		e := base
+		e.Kind, e.Direction, e.Counterparty = "token", "out", to
+		switch {
+		case from == to:
+			e.Direction = "self"
+		case to == addr:
+			e.Direction, e.Counterparty = "in", from
+		}
+		token := l.Address
+		u := b.Token(token)
+		e.Asset, e.Token, e.Value = u.Name, &token, new(big.Int).SetBytes(l.Data)
+		e.Amount = units.Format(e.Value, u.Decimals, units.Exact)
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// Fee returns the ETH paid for gas by the sender of tx.
+func Fee(tx *types.Transaction, receipt *types.Receipt) *big.Int {
+	price := receipt.EffectiveGasPrice
+	if price == nil {
+		price = tx.GasPrice()
+	}
+	return new(big.Int).Mul(price, new(big.Int).SetUint64(receipt.GasUsed))
+}
@@ -0,0 +1,54 @@
+package history
+
+import (
+	"context"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"yunlabs.com/goethereumbook/index"
+)
+
+// IndexSource reads transactions from the local index. The index already
+// relates ERC20 Transfer parties, and internal transfer parties when it was
+// built with call traces, to their transactions.
+type IndexSource struct {
+	Index *index.Indexer
+}
+
+// Refs implements Source.
+func (s *IndexSource) Refs(ctx context.Context, addr common.Address, from, to uint64) ([]Ref, error) {
+	hashes, err := s.Index.AddressTxs(addr, from, to, 0)
+	if err != nil {
+		return nil, err
+	}
+	refs := make([]Ref, 0, len(hashes))
+	for _, hash := range hashes {
+		tx, err := s.Index.Transaction(hash)
+		if err != nil {
+			return nil, err
+		}
+		refs = append(refs, Ref{Block: tx.BlockNumber, Index: tx.Index, Hash: hash})
+	}
+	return refs, nil
+}
+
+// Load implements Source.
+func (s *IndexSource) Load(ctx context.Context, ref Ref) (*Tx, error) {
+	tx, err := s.Index.Transaction(ref.Hash)
+	if err != nil {
+		return nil, err
+	}
+	block, err := s.Index.BlockByNumber(tx.BlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return &Tx{
+		Block:    tx.BlockNumber,
+		Time:     block.Time,
+		Index:    tx.Index,
+		From:     tx.From,
+		Tx:       tx.Tx,
+		Receipt:  tx.Receipt,
+		Internal: tx.Internal,
+	}, nil
+}
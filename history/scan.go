@@ -0,0 +1,249 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"yunlabs.com/goethereumbook/trace"
+)
+
+// logChunk 是每次 FilterLogs 查询的区块数，很多节点限制单次查询的范围
+const logChunk = 2000
+
+// Client is the part of ethclient.Client the scanner needs. The simulated
+// backend implements it as well.
+type Client interface {
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	TransactionByHash(ctx context.Context, hash common.Hash) (*types.Transaction, bool, error)
+	TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+}
+
+// Scanner finds transactions by fetching every block of the range from the
+// node, and ERC20 Transfers with log filters. It is slow for long ranges;
+// the local index is the better source when available.
+type Scanner struct {
+	Client Client
+	// Tracer, when set, is used to find internal ETH transfers through
+	// debug_traceBlockByHash. Nodes without the debug API turn it off.
+	Tracer  *rpc.Client
+	Workers int
+	// Progress is called after each block with the number of blocks done.
+	Progress func(done, total uint64)
+
+	mu       sync.Mutex
+	found    map[common.Hash]*Tx // 扫描区块时匹配到的交易，Load 时只需再取收据
+	internal map[common.Hash][]trace.Transfer
+
+	traceFailed bool
+}
+
+// Refs implements Source.
+func (s *Scanner) Refs(ctx context.Context, addr common.Address, from, to uint64) ([]Ref, error) {
+	s.found = make(map[common.Hash]*Tx)
+	s.internal = make(map[common.Hash][]trace.Transfer)
+
+	if err := s.scanBlocks(ctx, addr, from, to); err != nil {
+		return nil, err
+	}
+	refs := make(map[common.Hash]Ref)
+	for hash, tx := range s.found {
+		refs[hash] = Ref{Block: tx.Block, Index: tx.Index, Hash: hash}
+	}
+
+	// ERC20 Transfer 的 from 和 to 分别是第 1、2 个 topic
+	party := []common.Hash{common.BytesToHash(addr.Bytes())}
+	for _, topics := range [][][]common.Hash{{{TransferTopic}, party}, {{TransferTopic}, nil, party}} {
+		for start := from; start <= to; start += logChunk {
+			end := start + logChunk - 1
+			if end > to {
+				end = to
+			}
+			logs, err := s.Client.FilterLogs(ctx, ethereum.FilterQuery{
+				FromBlock: new(big.Int).SetUint64(start),
+				ToBlock:   new(big.Int).SetUint64(end),
+				Topics:    topics,
+			})
+			if err != nil {
+				return nil, err
+			}
+			for _, l := range logs {
+				refs[l.TxHash] = Ref{Block: l.BlockNumber, Index: l.TxIndex, Hash: l.TxHash}
+			}
+		}
+	}
+
+	list := make([]Ref, 0, len(refs))
+	for _, ref := range refs {
+		list = append(list, ref)
+	}
+	return list, nil
+}
+
+// scanBlocks 用多个 worker 并发取区块，记录 from/to 或创建的合约是 addr 的交易
+func (s *Scanner) scanBlocks(ctx context.Context, addr common.Address, from, to uint64) error {
+	workers := s.Workers
+	if workers <= 0 {
+		workers = 8
+	}
+	numbers := make(chan uint64)
+	errc := make(chan error, workers)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg   sync.WaitGroup
+		done uint64
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for number := range numbers {
+				if err := s.scanBlock(ctx, addr, number); err != nil {
+					errc <- err
+					cancel()
+					return
+				}
+				s.mu.Lock()
+				done++
+				if s.Progress != nil {
+					s.Progress(done, to-from+1)
+				}
+				s.mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for number := from; number <= to; number++ {
+		select {
+		case numbers <- number:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(numbers)
+	wg.Wait()
+
+	select {
+	case err := <-errc:
+		return err
+	default:
+		return ctx.Err()
+	}
+}
+
+func (s *Scanner) scanBlock(ctx context.Context, addr common.Address, number uint64) error {
+	block, err := s.Client.BlockByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return err
+	}
+	if len(block.Transactions()) == 0 {
+		return nil
+	}
+	internal := s.blockTransfers(ctx, block)
+
+	for i, tx := range block.Transactions() {
+		from, err := sender(tx)
+		if err != nil {
+			return err
+		}
+		match := from == addr || tx.To() != nil && *tx.To() == addr
+		// 创建合约的交易要看收据中的合约地址
+		if !match && tx.To() == nil {
+			receipt, err := s.Client.TransactionReceipt(ctx, tx.Hash())
+			if err != nil {
+				return err
+			}
+			match = receipt.ContractAddress == addr
+		}
+		for _, t := range internal[tx.Hash()] {
+			match = match || t.From == addr || t.To == addr
+		}
+		if match {
+			s.mu.Lock()
+			s.found[tx.Hash()] = &Tx{Block: number, Time: block.Time(), Index: uint(i), From: from, Tx: tx}
+			s.mu.Unlock()
+		}
+	}
+	return nil
+}
+
+// blockTransfers 取区块的内部转账并缓存，节点不支持时关闭追踪
+func (s *Scanner) blockTransfers(ctx context.Context, block *types.Block) map[common.Hash][]trace.Transfer {
+	s.mu.Lock()
+	tracer := s.Tracer
+	s.mu.Unlock()
+	if tracer == nil {
+		return nil
+	}
+	transfers, err := trace.BlockTransfers(ctx, tracer, block)
+	if err != nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		switch {
+		case errors.Is(err, trace.ErrNoDebug) && s.Tracer != nil:
+			log.Printf("%v, scanning without internal transfers", err)
+			s.Tracer = nil
+		case !s.traceFailed:
+			// 非归档节点上较早的区块没有状态，只提示一次，这些区块的内部转账会缺失
+			log.Printf("trace block %x: %v, internal transfers of such blocks are skipped", block.Hash(), err)
+			s.traceFailed = true
+		}
+		return nil
+	}
+	s.mu.Lock()
+	for h, list := range transfers {
+		s.internal[h] = list
+	}
+	s.mu.Unlock()
+	return transfers
+}
+
+// Load implements Source.
+func (s *Scanner) Load(ctx context.Context, ref Ref) (*Tx, error) {
+	s.mu.Lock()
+	found := s.found[ref.Hash]
+	internal := s.internal[ref.Hash]
+	s.mu.Unlock()
+
+	tx := new(Tx)
+	if found != nil {
+		*tx = *found
+	} else {
+		// 只由 Transfer 日志找到的交易
+		t, _, err := s.Client.TransactionByHash(ctx, ref.Hash)
+		if err != nil {
+			return nil, err
+		}
+		from, err := sender(t)
+		if err != nil {
+			return nil, err
+		}
+		header, err := s.Client.HeaderByNumber(ctx, new(big.Int).SetUint64(ref.Block))
+		if err != nil {
+			return nil, err
+		}
+		*tx = Tx{Block: ref.Block, Time: header.Time, Index: ref.Index, From: from, Tx: t}
+	}
+	receipt, err := s.Client.TransactionReceipt(ctx, ref.Hash)
+	if err != nil {
+		return nil, err
+	}
+	tx.Receipt, tx.Internal = receipt, internal
+	return tx, nil
+}
+
+// sender 按交易自带的链 ID 恢复发送方，不需要先向节点查询链 ID
+func sender(tx *types.Transaction) (common.Address, error) {
+	return types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+}
@@ -0,0 +1,329 @@
+// Package index 把链上的区块、交易、收据和日志保存到本地 leveldb 中，
+// 并按地址、交易哈希和 topic 建立索引，查询历史时不再依赖归档节点。
+package index
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"yunlabs.com/goethereumbook/follower"
+	"yunlabs.com/goethereumbook/trace"
+)
+
+// transferTopic 即 keccak256("Transfer(address,address,uint256)")
+var transferTopic = crypto.Keccak256Hash([]byte("Transfer(address,address,uint256)"))
+
+// seedHeaders 是重启时交给 follower 的已索引区块头个数
+const seedHeaders = 128
+
+// ErrNotFound is returned when the requested item is not in the index.
+var ErrNotFound = errors.New("not found in index")
+
+// Indexer follows the chain through client and writes everything into db.
+type Indexer struct {
+	db      ethdb.KeyValueStore
+	client  *ethclient.Client
+	chainID *big.Int
+	signer  types.Signer
+	tracer  *rpc.Client // 不为 nil 时从调用追踪中提取内部转账
+}
+
+// Open opens (or creates) the leveldb database at path.
+func Open(path string, readonly bool) (ethdb.KeyValueStore, error) {
+	return leveldb.New(path, 16, 16, "", readonly)
+}
+
+// New creates an indexer. client may be nil when the index is only queried.
+func New(db ethdb.KeyValueStore, client *ethclient.Client) *Indexer {
+	return &Indexer{db: db, client: client}
+}
+
+// TraceTransfers makes IndexBlock extract the internal ETH transfers of
+// every transaction from call traces fetched through c. The parties of
+// those transfers are indexed as touching the transaction. Nodes without
+// the debug API turn it off again with a warning.
+func (ix *Indexer) TraceTransfers(c *rpc.Client) {
+	ix.tracer = c
+}
+
+// Head returns the number of the latest indexed block.
+func (ix *Indexer) Head() (uint64, bool) {
+	enc, err := ix.db.Get(headKey)
+	if err != nil || len(enc) != 8 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint64(enc), true
+}
+
+// CanonicalHash returns the hash of the indexed block at number.
+func (ix *Indexer) CanonicalHash(number uint64) (common.Hash, bool) {
+	enc, err := ix.db.Get(canonicalKey(number))
+	if err != nil || len(enc) != common.HashLength {
+		return common.Hash{}, false
+	}
+	return common.BytesToHash(enc), true
+}
+
+// Follow indexes the blocks delivered by f until ctx is cancelled. The
+// follower is seeded with the latest indexed headers, so blocks reorged out
+// while the indexer was offline are rolled back first.
+func (ix *Indexer) Follow(ctx context.Context, f *follower.Follower) error {
+	if err := ix.checkChainID(ctx); err != nil {
+		return err
+	}
+	headers, err := ix.RecentHeaders(seedHeaders)
+	if err != nil {
+		return err
+	}
+	f.Seed(headers)
+
+	return f.Run(ctx, func(ev follower.Event) error {
+		if ev.Type == follower.Removed {
+			log.Printf("rolling back block %d %x", ev.Header.Number, ev.Header.Hash())
+			return ix.Rollback(ev.Header.Number.Uint64())
+		}
+		block, err := ix.client.BlockByHash(ctx, ev.Header.Hash())
+		if err != nil {
+			return err
+		}
+		return ix.IndexBlock(ctx, block)
+	})
+}
+
+// RecentHeaders returns up to n of the latest indexed headers, oldest first.
+func (ix *Indexer) RecentHeaders(n int) ([]*types.Header, error) {
+	head, ok := ix.Head()
+	if !ok {
+		return nil, nil
+	}
+	var headers []*types.Header
+	for number := head; len(headers) < n; number-- {
+		hash, ok := ix.CanonicalHash(number)
+		if !ok {
+			break
+		}
+		enc, err := ix.db.Get(headerKey(hash))
+		if err != nil {
+			return nil, err
+		}
+		header := new(types.Header)
+		if err := rlp.DecodeBytes(enc, header); err != nil {
+			return nil, err
+		}
+		headers = append([]*types.Header{header}, headers...)
+		if number == 0 {
+			break
+		}
+	}
+	return headers, nil
+}
+
+// IndexBlock writes block, its transactions, receipts and logs into the index
+// and marks it as the canonical block at its height.
+func (ix *Indexer) IndexBlock(ctx context.Context, block *types.Block) error {
+	batch := ix.db.NewBatch()
+	number := block.NumberU64()
+
+	record := &Block{
+		Number:     number,
+		Hash:       block.Hash(),
+		ParentHash: block.ParentHash(),
+		Time:       block.Time(),
+		Miner:      block.Coinbase(),
+		GasUsed:    block.GasUsed(),
+		GasLimit:   block.GasLimit(),
+		BaseFee:    block.BaseFee(),
+	}
+
+	// 整个区块的调用追踪一次取回，合约内部转出的 ETH 在收据里看不到
+	var internal map[common.Hash][]trace.Transfer
+	if ix.tracer != nil && len(block.Transactions()) > 0 {
+		var err error
+		internal, err = trace.BlockTransfers(ctx, ix.tracer, block)
+		if errors.Is(err, trace.ErrNoDebug) {
+			log.Printf("%v, indexing without internal transfers", err)
+			ix.tracer = nil
+		} else if err != nil {
+			return fmt.Errorf("trace block %d: %v", number, err)
+		}
+	}
+	for i, tx := range block.Transactions() {
+		from, err := types.Sender(ix.signer, tx)
+		if err != nil {
+			return fmt.Errorf("tx %x: %v", tx.Hash(), err)
+		}
+		receipt, err := ix.client.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			return fmt.Errorf("receipt %x: %v", tx.Hash(), err)
+		}
+		txRecord := &Tx{
+			BlockNumber: number,
+			BlockHash:   block.Hash(),
+			Index:       uint(i),
+			From:        from,
+			Tx:          tx,
+			Receipt:     receipt,
+			Internal:    internal[tx.Hash()],
+		}
+		enc, err := json.Marshal(txRecord)
+		if err != nil {
+			return err
+		}
+		if err := batch.Put(txKey(tx.Hash()), enc); err != nil {
+			return err
+		}
+		for _, key := range txIndexKeys(txRecord) {
+			if err := batch.Put(key, tx.Hash().Bytes()); err != nil {
+				return err
+			}
+		}
+		record.Transactions = append(record.Transactions, tx.Hash())
+	}
+
+	enc, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	if err := batch.Put(blockKey(block.Hash()), enc); err != nil {
+		return err
+	}
+	header, err := rlp.EncodeToBytes(block.Header())
+	if err != nil {
+		return err
+	}
+	if err := batch.Put(headerKey(block.Hash()), header); err != nil {
+		return err
+	}
+	if err := batch.Put(canonicalKey(number), block.Hash().Bytes()); err != nil {
+		return err
+	}
+	if err := batch.Put(headKey, encodeNumber(number)); err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// Rollback removes the indexed block at number together with everything
+// derived from it, and moves the head back to its parent.
+func (ix *Indexer) Rollback(number uint64) error {
+	hash, ok := ix.CanonicalHash(number)
+	if !ok {
+		return ErrNotFound
+	}
+	block, err := ix.BlockByHash(hash)
+	if err != nil {
+		return err
+	}
+
+	batch := ix.db.NewBatch()
+	for _, txHash := range block.Transactions {
+		tx, err := ix.Transaction(txHash)
+		if err != nil {
+			return err
+		}
+		for _, key := range txIndexKeys(tx) {
+			if err := batch.Delete(key); err != nil {
+				return err
+			}
+		}
+		if err := batch.Delete(txKey(txHash)); err != nil {
+			return err
+		}
+	}
+	if err := batch.Delete(blockKey(hash)); err != nil {
+		return err
+	}
+	if err := batch.Delete(headerKey(hash)); err != nil {
+		return err
+	}
+	if err := batch.Delete(canonicalKey(number)); err != nil {
+		return err
+	}
+	if number == 0 {
+		err = batch.Delete(headKey)
+	} else {
+		err = batch.Put(headKey, encodeNumber(number-1))
+	}
+	if err != nil {
+		return err
+	}
+	return batch.Write()
+}
+
+// Close closes the underlying database.
+func (ix *Indexer) Close() error {
+	return ix.db.Close()
+}
+
+// checkChainID 记录首次索引时的链ID，之后连接到别的链时直接报错
+func (ix *Indexer) checkChainID(ctx context.Context) error {
+	if ix.signer != nil {
+		return nil
+	}
+	chainID, err := ix.client.ChainID(ctx)
+	if err != nil {
+		return err
+	}
+	if enc, err := ix.db.Get(chainIDKey); err == nil {
+		if stored := new(big.Int).SetBytes(enc); stored.Cmp(chainID) != 0 {
+			return fmt.Errorf("index was built for chain %v, node is on chain %v", stored, chainID)
+		}
+	} else if err := ix.db.Put(chainIDKey, chainID.Bytes()); err != nil {
+		return err
+	}
+	ix.chainID = chainID
+	ix.signer = types.LatestSignerForChainID(chainID)
+	return nil
+}
+
+// txIndexKeys 返回一笔交易在地址索引和 topic 索引中的所有键，写入和回滚共用
+func txIndexKeys(tx *Tx) [][]byte {
+	var (
+		keys  [][]byte
+		seen  = make(map[common.Address]bool)
+		addrs = []common.Address{tx.From}
+	)
+	if to := tx.Tx.To(); to != nil {
+		addrs = append(addrs, *to)
+	}
+	if tx.Receipt != nil {
+		if tx.Receipt.ContractAddress != (common.Address{}) {
+			addrs = append(addrs, tx.Receipt.ContractAddress)
+		}
+		for _, l := range tx.Receipt.Logs {
+			addrs = append(addrs, l.Address)
+			// ERC20 Transfer 的双方也算作与该地址相关的交易
+			if len(l.Topics) == 3 && l.Topics[0] == transferTopic {
+				addrs = append(addrs, common.BytesToAddress(l.Topics[1].Bytes()), common.BytesToAddress(l.Topics[2].Bytes()))
+			}
+			for _, topic := range l.Topics {
+				keys = append(keys, topicKey(topic, tx.BlockNumber, l.Index))
+			}
+		}
+	}
+	// 内部转账的双方(如合约退款的收款人)也算作与该地址相关的交易
+	for _, t := range tx.Internal {
+		addrs = append(addrs, t.From, t.To)
+	}
+	for _, addr := range addrs {
+		if !seen[addr] {
+			seen[addr] = true
+			keys = append(keys, addressKey(addr, tx.BlockNumber, tx.Index))
+		}
+	}
+	return keys
+}
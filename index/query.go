@@ -0,0 +1,99 @@
+package index
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// BlockByNumber returns the canonical indexed block at number.
+func (ix *Indexer) BlockByNumber(number uint64) (*Block, error) {
+	hash, ok := ix.CanonicalHash(number)
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return ix.BlockByHash(hash)
+}
+
+// BlockByHash returns the indexed block with the given hash.
+func (ix *Indexer) BlockByHash(hash common.Hash) (*Block, error) {
+	enc, err := ix.db.Get(blockKey(hash))
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	block := new(Block)
+	if err := json.Unmarshal(enc, block); err != nil {
+		return nil, err
+	}
+	return block, nil
+}
+
+// Transaction returns the indexed transaction and its receipt.
+func (ix *Indexer) Transaction(hash common.Hash) (*Tx, error) {
+	enc, err := ix.db.Get(txKey(hash))
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	tx := new(Tx)
+	if err := json.Unmarshal(enc, tx); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// AddressTxs returns the hashes of the transactions touching addr in blocks
+// [from, to], oldest first. A limit of 0 means no limit.
+func (ix *Indexer) AddressTxs(addr common.Address, from, to uint64, limit int) ([]common.Hash, error) {
+	prefix := append(append([]byte{}, addressPrefix...), addr.Bytes()...)
+	return ix.scan(prefix, from, to, limit)
+}
+
+// TopicLogs returns the logs carrying topic at any position in blocks
+// [from, to], oldest first. A limit of 0 means no limit.
+func (ix *Indexer) TopicLogs(topic common.Hash, from, to uint64, limit int) ([]*types.Log, error) {
+	prefix := append(append([]byte{}, topicPrefix...), topic.Bytes()...)
+	it := ix.db.NewIterator(prefix, encodeNumber(from))
+	defer it.Release()
+
+	var logs []*types.Log
+	for it.Next() {
+		key := it.Key()[len(prefix):]
+		if binary.BigEndian.Uint64(key[:8]) > to {
+			break
+		}
+		logIndex := uint(binary.BigEndian.Uint32(key[8:]))
+		tx, err := ix.Transaction(common.BytesToHash(it.Value()))
+		if err != nil {
+			return nil, err
+		}
+		for _, l := range tx.Receipt.Logs {
+			if l.Index == logIndex {
+				logs = append(logs, l)
+			}
+		}
+		if limit > 0 && len(logs) >= limit {
+			break
+		}
+	}
+	return logs, it.Error()
+}
+
+// scan 遍历 prefix + num(8) + idx(4) 形式的索引，返回区块范围内的交易哈希
+func (ix *Indexer) scan(prefix []byte, from, to uint64, limit int) ([]common.Hash, error) {
+	it := ix.db.NewIterator(prefix, encodeNumber(from))
+	defer it.Release()
+
+	var hashes []common.Hash
+	for it.Next() {
+		if binary.BigEndian.Uint64(it.Key()[len(prefix):]) > to {
+			break
+		}
+		hashes = append(hashes, common.BytesToHash(it.Value()))
+		if limit > 0 && len(hashes) >= limit {
+			break
+		}
+	}
+	return hashes, it.Error()
+}
@@ -0,0 +1,88 @@
+package index
+
+import (
+	"encoding/binary"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"yunlabs.com/goethereumbook/trace"
+)
+
+// 数据库中的键布局，数字一律使用大端编码，保证按前缀迭代时有序
+var (
+	headKey    = []byte("LastBlock") // 已索引的最新区块号
+	chainIDKey = []byte("ChainID")   // 建库时的链ID，防止混用不同链的数据
+
+	canonicalPrefix = []byte("n") // n + num(8) -> block hash
+	blockPrefix     = []byte("b") // b + hash -> Block (json)
+	headerPrefix    = []byte("h") // h + hash -> header (rlp)，重启时用来恢复 follower 的窗口
+	txPrefix        = []byte("t") // t + hash -> Tx (json)
+	addressPrefix   = []byte("a") // a + address + num(8) + txIndex(4) -> tx hash
+	topicPrefix     = []byte("o") // o + topic + num(8) + logIndex(4) -> tx hash
+)
+
+// Block 是索引中保存的区块摘要
+type Block struct {
+	Number       uint64         `json:"number"`
+	Hash         common.Hash    `json:"hash"`
+	ParentHash   common.Hash    `json:"parentHash"`
+	Time         uint64         `json:"timestamp"`
+	Miner        common.Address `json:"miner"`
+	GasUsed      uint64         `json:"gasUsed"`
+	GasLimit     uint64         `json:"gasLimit"`
+	BaseFee      *big.Int       `json:"baseFeePerGas,omitempty"`
+	Transactions []common.Hash  `json:"transactions"`
+}
+
+// Tx 是索引中保存的交易，连同发送者和收据一起存放；开启追踪时还有内部转账
+type Tx struct {
+	BlockNumber uint64             `json:"blockNumber"`
+	BlockHash   common.Hash        `json:"blockHash"`
+	Index       uint               `json:"transactionIndex"`
+	From        common.Address     `json:"from"`
+	Tx          *types.Transaction `json:"tx"`
+	Receipt     *types.Receipt     `json:"receipt"`
+	Internal    []trace.Transfer   `json:"internal,omitempty"`
+}
+
+func encodeNumber(number uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, number)
+	return enc
+}
+
+func encodeIndex(idx uint) []byte {
+	enc := make([]byte, 4)
+	binary.BigEndian.PutUint32(enc, uint32(idx))
+	return enc
+}
+
+func canonicalKey(number uint64) []byte {
+	return append(append([]byte{}, canonicalPrefix...), encodeNumber(number)...)
+}
+
+func blockKey(hash common.Hash) []byte {
+	return append(append([]byte{}, blockPrefix...), hash.Bytes()...)
+}
+
+func headerKey(hash common.Hash) []byte {
+	return append(append([]byte{}, headerPrefix...), hash.Bytes()...)
+}
+
+func txKey(hash common.Hash) []byte {
+	return append(append([]byte{}, txPrefix...), hash.Bytes()...)
+}
+
+func addressKey(addr common.Address, number uint64, txIndex uint) []byte {
+	key := append(append([]byte{}, addressPrefix...), addr.Bytes()...)
+	key = append(key, encodeNumber(number)...)
+	return append(key, encodeIndex(txIndex)...)
+}
+
+func topicKey(topic common.Hash, number uint64, logIndex uint) []byte {
+	key := append(append([]byte{}, topicPrefix...), topic.Bytes()...)
+	key = append(key, encodeNumber(number)...)
+	return append(key, encodeIndex(logIndex)...)
+}
@@ -0,0 +1,302 @@
+// Package mempool 订阅节点的 newPendingTransactions，按条件过滤待打包交易，
+// 并跟踪每笔交易何时被打包或被丢弃，统计打包耗时。
+package mempool
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+
+	"yunlabs.com/goethereumbook/follower"
+	"yunlabs.com/goethereumbook/registry"
+)
+
+// Filter selects the pending transactions to watch. Empty fields match all.
+type Filter struct {
+	From      []common.Address
+	To        []common.Address
+	MinValue  *big.Int
+	Selectors [][]byte
+}
+
+// Match reports whether tx sent by from passes the filter.
+func (f *Filter) Match(from common.Address, tx *types.Transaction) bool {
+	if len(f.From) > 0 && !containsAddress(f.From, from) {
+		return false
+	}
+	if len(f.To) > 0 && (tx.To() == nil || !containsAddress(f.To, *tx.To())) {
+		return false
+	}
+	if f.MinValue != nil && tx.Value().Cmp(f.MinValue) < 0 {
+		return false
+	}
+	if len(f.Selectors) > 0 {
+		data := tx.Data()
+		if len(data) < 4 {
+			return false
+		}
+		for _, sel := range f.Selectors {
+			if bytes.Equal(data[:4], sel) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func containsAddress(list []common.Address, addr common.Address) bool {
+	for _, a := range list {
+		if a == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// Status is the lifecycle state of a watched transaction.
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusMined   Status = "mined"
+	StatusDropped Status = "dropped"
+)
+
+// Tx is a watched pending transaction.
+type Tx struct {
+	Tx     *types.Transaction
+	From   common.Address
+	Call   *registry.Call // 无法解码时为 nil
+	Seen   time.Time
+	Status Status
+	Block  uint64        // 打包所在区块
+	Waited time.Duration // 从看到交易到被打包或丢弃的时间
+	Reason string        // 丢弃原因
+}
+
+// Watcher subscribes to pending transactions and tracks them until they are
+// mined or dropped.
+type Watcher struct {
+	Filter    Filter
+	Registry  *registry.Registry
+	DropAfter time.Duration // 超过这个时间仍未打包就视为丢弃
+	OnUpdate  func(*Tx)     // 交易状态变化时回调
+	Workers   int           // 并发获取待打包交易的 goroutine 数
+
+	ws      *rpc.Client
+	client  *ethclient.Client
+	signer  types.Signer
+	pending map[common.Hash]*Tx
+	stats   Stats
+}
+
+// NewWatcher creates a watcher. ws must be a websocket rpc client, client is
+// used for fetching transactions, nonces and blocks.
+func NewWatcher(ws *rpc.Client, client *ethclient.Client) *Watcher {
+	return &Watcher{
+		Registry:  registry.Default(),
+		DropAfter: 10 * time.Minute,
+		Workers:   8,
+		ws:        ws,
+		client:    client,
+		pending:   make(map[common.Hash]*Tx),
+	}
+}
+
+// Run watches the mempool until ctx is cancelled.
+func (w *Watcher) Run(ctx context.Context) error {
+	chainID, err := w.client.ChainID(ctx)
+	if err != nil {
+		return err
+	}
+	w.signer = types.LatestSignerForChainID(chainID)
+
+	hashes := make(chan common.Hash, 256)
+	sub, err := w.ws.EthSubscribe(ctx, hashes, "newPendingTransactions")
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	// 交易内容由一组 goroutine 获取，节点响应慢时不会卡住订阅的读取
+	found := make(chan *Tx, 256)
+	workers := w.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		go func() {
+			for {
+				select {
+				case hash := <-hashes:
+					if ptx := w.fetch(ctx, hash); ptx != nil {
+						select {
+						case found <- ptx:
+						case <-ctx.Done():
+							return
+						}
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	// 新区块用来判断交易是否已被打包
+	blocks := make(chan *types.Header)
+	followErr := make(chan error, 1)
+	go func() {
+		f := follower.New(w.client, follower.Config{})
+		followErr <- f.Run(ctx, func(ev follower.Event) error {
+			if ev.Type == follower.Added {
+				select {
+				case blocks <- ev.Header:
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			return err
+		case err := <-followErr:
+			return err
+		case ptx := <-found:
+			w.handlePending(ptx)
+		case header := <-blocks:
+			if err := w.handleBlock(ctx, header); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Stats returns the inclusion statistics collected so far.
+func (w *Watcher) Stats() Stats {
+	return w.stats
+}
+
+// fetch 获取并过滤一笔待打包交易，在 worker goroutine 中执行，不访问 pending
+func (w *Watcher) fetch(ctx context.Context, hash common.Hash) *Tx {
+	tx, isPending, err := w.client.TransactionByHash(ctx, hash)
+	if err != nil || !isPending {
+		return nil // 已经被打包或从交易池移除
+	}
+	from, err := types.Sender(w.signer, tx)
+	if err != nil || !w.Filter.Match(from, tx) {
+		return nil
+	}
+	ptx := &Tx{Tx: tx, From: from, Seen: time.Now(), Status: StatusPending}
+	if w.Registry != nil {
+		ptx.Call, _ = w.Registry.DecodeCall(tx.Data())
+	}
+	return ptx
+}
+
+func (w *Watcher) handlePending(ptx *Tx) {
+	hash := ptx.Tx.Hash()
+	if _, ok := w.pending[hash]; ok {
+		return
+	}
+	w.pending[hash] = ptx
+	w.stats.Seen++
+	w.update(ptx)
+}
+
+func (w *Watcher) handleBlock(ctx context.Context, header *types.Header) error {
+	if len(w.pending) == 0 {
+		return nil
+	}
+	block, err := w.client.BlockByHash(ctx, header.Hash())
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, tx := range block.Transactions() {
+		if ptx, ok := w.pending[tx.Hash()]; ok {
+			ptx.Status, ptx.Block, ptx.Waited = StatusMined, block.NumberU64(), now.Sub(ptx.Seen)
+			w.stats.addMined(ptx.Waited)
+			delete(w.pending, tx.Hash())
+			w.update(ptx)
+		}
+	}
+
+	// 同一发送者的 nonce 已经超过这笔交易，说明它被替换了；等待过久的也视为丢弃。
+	// 每个发送者每个区块只查一次 nonce
+	nonces := make(map[common.Address]uint64)
+	for hash, ptx := range w.pending {
+		nonce, ok := nonces[ptx.From]
+		if !ok {
+			nonce, err = w.client.NonceAt(ctx, ptx.From, block.Number())
+			if err != nil {
+				return err
+			}
+			nonces[ptx.From] = nonce
+		}
+		switch {
+		case nonce > ptx.Tx.Nonce():
+			ptx.Reason = fmt.Sprintf("replaced, sender nonce is %d", nonce)
+		case w.DropAfter > 0 && now.Sub(ptx.Seen) > w.DropAfter:
+			ptx.Reason = "timeout"
+		default:
+			continue
+		}
+		ptx.Status, ptx.Waited = StatusDropped, now.Sub(ptx.Seen)
+		w.stats.Dropped++
+		delete(w.pending, hash)
+		w.update(ptx)
+	}
+	return nil
+}
+
+func (w *Watcher) update(tx *Tx) {
+	if w.OnUpdate != nil {
+		w.OnUpdate(tx)
+	}
+}
+
+// Stats summarizes the time from first sight in the mempool to inclusion.
+type Stats struct {
+	Seen    int
+	Mined   int
+	Dropped int
+	waits   []time.Duration
+}
+
+func (s *Stats) addMined(d time.Duration) {
+	s.Mined++
+	s.waits = append(s.waits, d)
+}
+
+// Percentile returns the p-th percentile (0-100) of time-to-inclusion.
+func (s Stats) Percentile(p float64) time.Duration {
+	if len(s.waits) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration{}, s.waits...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := int(p / 100 * float64(len(sorted)-1))
+	return sorted[idx]
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("seen=%d mined=%d dropped=%d pending=%d inclusion min=%v p50=%v p90=%v max=%v",
+		s.Seen, s.Mined, s.Dropped, s.Seen-s.Mined-s.Dropped,
+		s.Percentile(0), s.Percentile(50), s.Percentile(90), s.Percentile(100))
+}
@@ -0,0 +1,266 @@
+// Package monitor 把账户和合约的状态导出成 Prometheus 指标：ETH 和代币余额、nonce、待打包交易数、
+// 最新区块高度和出块间隔、代币总量，以及访问节点的延迟和错误数。
+// 指标在抓取时按需刷新，间隔小于 TTL 的抓取直接返回缓存的值，抓取再频繁也不会压垮节点。
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"yunlabs.com/goethereumbook/batch"
+	"yunlabs.com/goethereumbook/contracts/token"
+)
+
+// Config lists what to export.
+type Config struct {
+	Accounts []common.Address
+	// Tokens 导出每个账户的代币余额和代币的 TotalSupply
+	Tokens []common.Address
+	// Namespace 是所有指标名的前缀，默认 goethbook
+	Namespace string
+	// TTL 内的抓取使用上次读到的值，默认 15 秒
+	TTL time.Duration
+	// Timeout 是一次刷新的超时时间，默认 10 秒
+	Timeout time.Duration
+}
+
+// Exporter serves the metrics. Accounts and tokens are labels, so queries
+// and alerts can select and aggregate over them:
+//
+//	goethbook_block_height, goethbook_block_age_seconds
+//	goethbook_account_balance{account, token}   token is "ETH" or the token address
+//	goethbook_account_nonce{account}, goethbook_account_pending{account}
+//	goethbook_token_total_supply{token}
+//	goethbook_rpc_latency_seconds, goethbook_rpc_requests_total, goethbook_rpc_errors_total
+//	goethbook_up
+//
+// Addresses are lower case hex. Balances and supplies are floats in ETH or
+// whole tokens. Requests and errors count single calls: every element of
+// the JSON-RPC batch and every call of the Multicall batch.
+type Exporter struct {
+	rpc   *rpc.Client
+	batch *batch.Client
+	cfg   Config
+	abi   *abi.ABI
+	reg   *prometheus.Registry
+
+	mu       sync.Mutex
+	checked  time.Time // 上次刷新的时间，失败也算，避免节点出错时每次抓取都去重试
+	time     uint64    // 最新区块的时间戳
+	decimals map[common.Address]uint8
+
+	height, age, up         prometheus.Gauge
+	latency                 prometheus.Histogram
+	requests, errs          prometheus.Counter
+	balance, nonce, pending *prometheus.GaugeVec
+	supply                  *prometheus.GaugeVec
+}
+
+// New registers the metrics.
+func New(c *rpc.Client, bc *batch.Client, cfg Config) (*Exporter, error) {
+	if cfg.Namespace == "" {
+		cfg.Namespace = "goethbook"
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 15 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	tokenABI, err := token.TokenMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	e := &Exporter{
+		rpc:      c,
+		batch:    bc,
+		cfg:      cfg,
+		abi:      tokenABI,
+		reg:      prometheus.NewRegistry(),
+		decimals: make(map[common.Address]uint8),
+	}
+	ns := cfg.Namespace
+	e.height = prometheus.NewGauge(prometheus.GaugeOpts{Namespace: ns, Subsystem: "block", Name: "height", Help: "Number of the latest block."})
+	e.age = prometheus.NewGauge(prometheus.GaugeOpts{Namespace: ns, Subsystem: "block", Name: "age_seconds", Help: "Seconds since the timestamp of the latest block."})
+	e.up = prometheus.NewGauge(prometheus.GaugeOpts{Namespace: ns, Name: "up", Help: "1 if the last refresh succeeded."})
+	e.latency = prometheus.NewHistogram(prometheus.HistogramOpts{Namespace: ns, Subsystem: "rpc", Name: "latency_seconds", Help: "Duration of a refresh from the node."})
+	e.requests = prometheus.NewCounter(prometheus.CounterOpts{Namespace: ns, Subsystem: "rpc", Name: "requests_total", Help: "Calls sent to the node."})
+	e.errs = prometheus.NewCounter(prometheus.CounterOpts{Namespace: ns, Subsystem: "rpc", Name: "errors_total", Help: "Calls to the node that failed."})
+	e.balance = prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: ns, Subsystem: "account", Name: "balance", Help: "Balance in ETH or whole tokens."}, []string{"account", "token"})
+	e.nonce = prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: ns, Subsystem: "account", Name: "nonce", Help: "Nonce at the latest block."}, []string{"account"})
+	e.pending = prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: ns, Subsystem: "account", Name: "pending", Help: "Transactions waiting in the pool."}, []string{"account"})
+	e.supply = prometheus.NewGaugeVec(prometheus.GaugeOpts{Namespace: ns, Subsystem: "token", Name: "total_supply", Help: "TotalSupply in whole tokens."}, []string{"token"})
+	for _, c := range []prometheus.Collector{e.height, e.age, e.up, e.latency, e.requests, e.errs, e.balance, e.nonce, e.pending, e.supply} {
+		if err := e.reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
+
+func hex(addr common.Address) string {
+	return strings.ToLower(addr.Hex())
+}
+
+// ServeHTTP refreshes the values when they are older than the TTL and
+// writes all metrics in the Prometheus text format.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	if time.Since(e.checked) >= e.cfg.TTL {
+		e.checked = time.Now()
+		ctx, cancel := context.WithTimeout(r.Context(), e.cfg.Timeout)
+		if err := e.Refresh(ctx); err != nil {
+			log.Printf("refresh metrics: %v", err)
+		}
+		cancel()
+	}
+	// 出块间隔每次抓取都按缓存的区块时间重新计算，不需要访问节点
+	if e.time > 0 {
+		e.age.Set(float64(time.Now().Unix() - int64(e.time)))
+	}
+	e.mu.Unlock()
+	promhttp.HandlerFor(e.reg, promhttp.HandlerOpts{}).ServeHTTP(w, r)
+}
+
+// Refresh reads everything from the node: one JSON-RPC batch for the
+// latest block, balances and nonces, one Multicall batch for the tokens.
+// On error the previous values are kept and up is set to 0.
+func (e *Exporter) Refresh(ctx context.Context) error {
+	start := time.Now()
+	err := e.refresh(ctx)
+	e.latency.Observe(time.Since(start).Seconds())
+	if err != nil {
+		e.up.Set(0)
+		return err
+	}
+	e.up.Set(1)
+	return nil
+}
+
+func (e *Exporter) refresh(ctx context.Context) error {
+	n := len(e.cfg.Accounts)
+	var header types.Header
+	balances := make([]hexutil.Big, n)
+	nonces := make([]hexutil.Uint64, n)
+	pending := make([]hexutil.Uint64, n)
+	elems := []rpc.BatchElem{{Method: "eth_getBlockByNumber", Args: []interface{}{"latest", false}, Result: &header}}
+	for i, addr := range e.cfg.Accounts {
+		elems = append(elems,
+			rpc.BatchElem{Method: "eth_getBalance", Args: []interface{}{addr, "latest"}, Result: &balances[i]},
+			rpc.BatchElem{Method: "eth_getTransactionCount", Args: []interface{}{addr, "latest"}, Result: &nonces[i]},
+			rpc.BatchElem{Method: "eth_getTransactionCount", Args: []interface{}{addr, "pending"}, Result: &pending[i]},
+		)
+	}
+	e.requests.Add(float64(len(elems)))
+	if err := e.rpc.BatchCallContext(ctx, elems); err != nil {
+		// 整个批量请求失败，其中每个调用都算失败
+		e.errs.Add(float64(len(elems)))
+		return err
+	}
+	var first error
+	for _, elem := range elems {
+		if elem.Error != nil {
+			e.errs.Inc()
+			if first == nil {
+				first = fmt.Errorf("%s %v: %v", elem.Method, elem.Args, elem.Error)
+			}
+		}
+	}
+	if first != nil {
+		return first
+	}
+
+	e.height.Set(float64(header.Number.Int64()))
+	e.time = header.Time
+	for i, addr := range e.cfg.Accounts {
+		e.balance.WithLabelValues(hex(addr), "ETH").Set(ether(balances[i].ToInt(), 18))
+		e.nonce.WithLabelValues(hex(addr)).Set(float64(nonces[i]))
+		// pending nonce 减去已确认的 nonce 就是还在交易池里的交易数
+		count := int64(pending[i]) - int64(nonces[i])
+		if count < 0 {
+			count = 0
+		}
+		e.pending.WithLabelValues(hex(addr)).Set(float64(count))
+	}
+	if len(e.cfg.Tokens) == 0 {
+		return nil
+	}
+	return e.tokens(ctx, header.Number)
+}
+
+// tokens 在同一个区块读取代币总量和所有账户的余额，decimals 只在第一次读取
+func (e *Exporter) tokens(ctx context.Context, number *big.Int) error {
+	var calls []*batch.Call
+	var missing []common.Address
+	for _, t := range e.cfg.Tokens {
+		if _, ok := e.decimals[t]; !ok {
+			missing = append(missing, t)
+			calls = append(calls, batch.NewCall(t, e.abi, "decimals"))
+		}
+	}
+	meta := len(calls)
+	for _, t := range e.cfg.Tokens {
+		calls = append(calls, batch.NewCall(t, e.abi, "totalSupply"))
+		for _, addr := range e.cfg.Accounts {
+			calls = append(calls, batch.NewCall(t, e.abi, "balanceOf", addr))
+		}
+	}
+	e.requests.Add(float64(len(calls)))
+	if err := e.batch.Do(&bind.CallOpts{Context: ctx, BlockNumber: number}, calls); err != nil {
+		e.errs.Add(float64(len(calls)))
+		return err
+	}
+	// 单个调用失败不影响其他调用，逐个计数
+	for _, call := range calls {
+		if call.Err != nil {
+			e.errs.Inc()
+		}
+	}
+	for i, t := range missing {
+		if calls[i].Err != nil {
+			return fmt.Errorf("token %s decimals: %v", t.Hex(), calls[i].Err)
+		}
+		e.decimals[t] = calls[i].Out[0].(uint8)
+	}
+
+	calls = calls[meta:]
+	for _, t := range e.cfg.Tokens {
+		decimals := e.decimals[t]
+		supply, err := calls[0].Big()
+		if err != nil {
+			return fmt.Errorf("token %s totalSupply: %v", t.Hex(), err)
+		}
+		e.supply.WithLabelValues(hex(t)).Set(ether(supply, decimals))
+		for i, addr := range e.cfg.Accounts {
+			bal, err := calls[1+i].Big()
+			if err != nil {
+				return fmt.Errorf("token %s balanceOf %s: %v", t.Hex(), addr.Hex(), err)
+			}
+			e.balance.WithLabelValues(hex(addr), hex(t)).Set(ether(bal, decimals))
+		}
+		calls = calls[1+len(e.cfg.Accounts):]
+	}
+	return nil
+}
+
+// ether 把最小单位的整数换成带小数的浮点数，监控用，精度损失可以接受
+func ether(v *big.Int, decimals uint8) float64 {
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(v), big.NewFloat(math.Pow10(int(decimals)))).Float64()
+	return f
+}
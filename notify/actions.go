@@ -0,0 +1,135 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// Webhook posts the notification as JSON. With a Secret the body is signed
+// with HMAC-SHA256 in the X-Notify-Signature header, sha256=<hex>, for the
+// receiver to check. Network errors, 429 and 5xx responses are retried
+// Retries times with exponential backoff.
+type Webhook struct {
+	URL     string            `yaml:"url"`
+	Secret  string            `yaml:"secret,omitempty"`
+	Headers map[string]string `yaml:"headers,omitempty"`
+	Retries *int              `yaml:"retries,omitempty"` // 默认 3
+	Backoff time.Duration     `yaml:"backoff,omitempty"` // 第一次重试前等待的时间，之后每次加倍，默认 1s
+
+	rawSecret string // 展开环境变量之前的 secret，用来发现没有设置的变量
+}
+
+// maxBackoff 是重试间隔的上限
+const maxBackoff = time.Minute
+
+// defaultTimeout 是没有配置 timeout 时单次请求或命令的超时时间
+const defaultTimeout = 10 * time.Second
+
+// permanent 是重试也不会成功的错误，比如 4xx 响应
+type permanent struct{ error }
+
+// Sign returns the X-Notify-Signature value of body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func (w *Webhook) deliver(ctx context.Context, n *Notification, body []byte, timeout time.Duration) error {
+	retries := 3
+	if w.Retries != nil {
+		retries = *w.Retries
+	}
+	backoff := w.Backoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	for attempt := 0; ; attempt++ {
+		err := w.post(ctx, n, body, timeout)
+		if err == nil || errors.As(err, &permanent{}) || attempt >= retries || ctx.Err() != nil {
+			return err
+		}
+		log.Printf("%s: webhook %s: %v, retrying in %v", n.Rule, w.URL, err, backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+func (w *Webhook) post(ctx context.Context, n *Notification, body []byte, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return permanent{err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "goethereumbook-notify")
+	// 同一条通知重试时 id 不变，接收方可以用来去重
+	req.Header.Set("X-Notify-Id", n.ID)
+	req.Header.Set("X-Notify-Rule", n.Rule)
+	if w.Secret != "" {
+		req.Header.Set("X-Notify-Signature", Sign(w.Secret, body))
+	}
+	for k, v := range w.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	switch {
+	case resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return permanent{fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))}
+}
+
+// run 执行命令，通知的 JSON 写到标准输入
+func run(ctx context.Context, command string, n *Notification, body []byte, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"NOTIFY_ID="+n.ID,
+		"NOTIFY_RULE="+n.Rule,
+		"NOTIFY_KIND="+n.Kind,
+		"NOTIFY_BLOCK="+strconv.FormatUint(n.BlockNumber, 10),
+		"NOTIFY_TX="+txHash(n),
+		"NOTIFY_MESSAGE="+n.Message,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%v: %s", err, bytes.TrimSpace(out))
+	}
+	return nil
+}
+
+func txHash(n *Notification) string {
+	if n.TxHash == nil {
+		return ""
+	}
+	return n.TxHash.Hex()
+}
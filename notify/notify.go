@@ -0,0 +1,450 @@
+// Package notify 按 YAML 规则监听链上的事件、大额 ETH 转账和余额变化，
+// 匹配时调用 webhook(JSON、HMAC 签名、失败退避重试)或执行本地命令。
+// 处理到的区块记录在检查点文件里，重启后从下一个区块继续。
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"yunlabs.com/goethereumbook/events"
+	"yunlabs.com/goethereumbook/output"
+	"yunlabs.com/goethereumbook/registry"
+	"yunlabs.com/goethereumbook/units"
+)
+
+// Backend is the part of ethclient.Client the notifier needs.
+type Backend interface {
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	BlockByNumber(ctx context.Context, number *big.Int) (*types.Block, error)
+	TransactionReceipt(ctx context.Context, hash common.Hash) (*types.Receipt, error)
+	FilterLogs(ctx context.Context, q ethereum.FilterQuery) ([]types.Log, error)
+	BalanceAt(ctx context.Context, account common.Address, number *big.Int) (*big.Int, error)
+}
+
+// Notification is what a rule sends: the JSON body of webhooks and the
+// standard input of commands.
+type Notification struct {
+	ID          string         `json:"id"`
+	Rule        string         `json:"rule"`
+	Kind        string         `json:"kind"` // event, transfer or balance
+	Message     string         `json:"message"`
+	BlockNumber uint64         `json:"blockNumber"`
+	BlockHash   common.Hash    `json:"blockHash"`
+	Time        time.Time      `json:"time"`
+	TxHash      *common.Hash   `json:"transactionHash,omitempty"`
+	Event       *output.Event  `json:"event,omitempty"`
+	Transfer    *Transfer      `json:"transfer,omitempty"`
+	Balance     *BalanceChange `json:"balance,omitempty"`
+}
+
+// Transfer is a matched ETH transfer.
+type Transfer struct {
+	From   common.Address `json:"from"`
+	To     common.Address `json:"to"`
+	Value  *big.Int       `json:"value"`
+	Amount string         `json:"amount"`
+}
+
+// BalanceChange is a balance that crossed a threshold.
+type BalanceChange struct {
+	Address   common.Address `json:"address"`
+	Balance   *big.Int       `json:"balance"`
+	Amount    string         `json:"amount"`
+	Threshold *big.Int       `json:"threshold"`
+	Below     bool           `json:"below"`
+}
+
+// Checkpoint is the progress saved after every block.
+type Checkpoint struct {
+	Block uint64      `json:"block"`
+	Hash  common.Hash `json:"hash"`
+	// Crossed 记录余额规则当前是否越过了阈值，只在越过的那一刻通知一次
+	Crossed map[string]bool `json:"crossed,omitempty"`
+}
+
+// Options configures the notifier.
+type Options struct {
+	Registry *registry.Registry
+	// Checkpoint 是检查点文件，为空时不保存进度
+	Checkpoint string
+	// From 是没有检查点时处理的第一个区块，nil 表示从最新的已确认区块开始
+	From *big.Int
+	Poll time.Duration
+	// DryRun 时把通知交给 Print，不执行动作
+	DryRun bool
+	Print  func(*Notification)
+	// Label 返回地址在地址簿中的标签，用在通知的 message 里
+	Label func(common.Address) string
+}
+
+// Notifier checks every confirmed block against the rules.
+type Notifier struct {
+	client        Backend
+	opts          Options
+	rules         []*rule
+	confirmations uint64
+	cp            Checkpoint
+}
+
+// New checks the rules of cfg and creates a notifier. resolve turns the
+// addresses of the rules, possibly address book names, into addresses.
+func New(client Backend, cfg *Config, resolve Resolver, opts Options) (*Notifier, error) {
+	if opts.Registry == nil {
+		opts.Registry = registry.Default()
+	}
+	if opts.Poll <= 0 {
+		opts.Poll = 2 * time.Second
+	}
+	if opts.Label == nil {
+		opts.Label = func(common.Address) string { return "" }
+	}
+	rules, err := compile(cfg, opts.Registry, resolve)
+	if err != nil {
+		return nil, err
+	}
+	if len(rules) == 0 {
+		return nil, errors.New("no rules")
+	}
+	return &Notifier{client: client, opts: opts, rules: rules, confirmations: cfg.Confirmations}, nil
+}
+
+// Run processes blocks as they are confirmed until ctx is done. Each block
+// is finished, all its actions delivered or given up, before the
+// checkpoint moves past it; a block that fails to load is retried at the
+// next poll.
+func (n *Notifier) Run(ctx context.Context) error {
+	next, err := n.start(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("notify: %d rules, starting at block %d", len(n.rules), next)
+	for {
+		head, err := n.client.HeaderByNumber(ctx, nil)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("latest block: %v", err)
+		} else if latest := head.Number.Uint64(); latest >= n.confirmations {
+			for ; next <= latest-n.confirmations; next++ {
+				if err := n.process(ctx, next); err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					// 节点暂时出错时下次轮询再试这个区块
+					log.Print(err)
+					break
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(n.opts.Poll):
+		}
+	}
+}
+
+// start 读取检查点，返回第一个要处理的区块
+func (n *Notifier) start(ctx context.Context) (uint64, error) {
+	n.cp = Checkpoint{Crossed: make(map[string]bool)}
+	if n.opts.Checkpoint != "" {
+		data, err := os.ReadFile(n.opts.Checkpoint)
+		switch {
+		case err == nil:
+			if err := json.Unmarshal(data, &n.cp); err != nil {
+				return 0, fmt.Errorf("%s: %v", n.opts.Checkpoint, err)
+			}
+			if n.cp.Crossed == nil {
+				n.cp.Crossed = make(map[string]bool)
+			}
+			// 停机期间检查点的区块被重组掉的话，已经发出的通知收不回来，只能提示一下
+			if header, err := n.client.HeaderByNumber(ctx, new(big.Int).SetUint64(n.cp.Block)); err == nil && header.Hash() != n.cp.Hash {
+				log.Printf("checkpoint block %d %x is no longer canonical", n.cp.Block, n.cp.Hash)
+			}
+			return n.cp.Block + 1, nil
+		case !errors.Is(err, os.ErrNotExist):
+			return 0, err
+		}
+	}
+	if n.opts.From != nil {
+		return n.opts.From.Uint64(), nil
+	}
+	head, err := n.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	if head.Number.Uint64() < n.confirmations {
+		return 0, nil
+	}
+	return head.Number.Uint64() - n.confirmations, nil
+}
+
+// process 检查一个区块，执行匹配规则的动作，然后保存检查点
+func (n *Notifier) process(ctx context.Context, number uint64) error {
+	block, err := n.client.BlockByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return fmt.Errorf("block %d: %v", number, err)
+	}
+	var found []*Notification
+	for _, match := range []func(context.Context, *types.Block) ([]*Notification, error){n.events, n.transfers, n.balances} {
+		list, err := match(ctx, block)
+		if err != nil {
+			return fmt.Errorf("block %d: %v", number, err)
+		}
+		found = append(found, list...)
+	}
+	for _, note := range found {
+		note.BlockNumber, note.BlockHash = number, block.Hash()
+		note.Time = time.Unix(int64(block.Time()), 0).UTC()
+	}
+	n.fire(ctx, found)
+	if ctx.Err() != nil {
+		// 中断时动作可能没有执行完，不保存这个区块，重启后重新处理
+		return ctx.Err()
+	}
+
+	n.cp.Block, n.cp.Hash = number, block.Hash()
+	return n.save()
+}
+
+func (n *Notifier) events(ctx context.Context, block *types.Block) ([]*Notification, error) {
+	var rules []*rule
+	for _, r := range n.rules {
+		if r.Event != nil {
+			rules = append(rules, r)
+		}
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	hash := block.Hash()
+	logs, err := n.client.FilterLogs(ctx, ethereum.FilterQuery{BlockHash: &hash})
+	if err != nil {
+		return nil, err
+	}
+	var found []*Notification
+	for i := range logs {
+		l := &logs[i]
+		ev, decoded := events.Decode(n.opts.Registry, l)
+		for _, r := range rules {
+			if !r.matchLog(l, decoded) {
+				continue
+			}
+			msg := fmt.Sprintf("%s log %d of %s", n.name(l.Address), l.Index, l.TxHash.Hex())
+			if decoded != nil {
+				msg = decoded.String() + " at " + n.name(l.Address)
+			}
+			event, tx := ev, l.TxHash
+			found = append(found, &Notification{
+				ID:      fmt.Sprintf("%s:%d:%d", r.Name, l.BlockNumber, l.Index),
+				Rule:    r.Name,
+				Kind:    "event",
+				Message: msg,
+				TxHash:  &tx,
+				Event:   &event,
+			})
+		}
+	}
+	return found, nil
+}
+
+func (r *rule) matchLog(l *types.Log, decoded *registry.Log) bool {
+	if r.contracts != nil && !r.contracts[l.Address] {
+		return false
+	}
+	if r.topic != nil && (len(l.Topics) == 0 || l.Topics[0] != *r.topic) {
+		return false
+	}
+	if len(r.where) == 0 {
+		return true
+	}
+	if decoded == nil {
+		return false
+	}
+	args := decoded.Map()
+	for name, c := range r.where {
+		v, ok := args[name]
+		if !ok || !c.match(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (n *Notifier) transfers(ctx context.Context, block *types.Block) ([]*Notification, error) {
+	var found []*Notification
+	for _, tx := range block.Transactions() {
+		if tx.To() == nil || tx.Value().Sign() == 0 {
+			continue
+		}
+		from, err := types.Sender(types.LatestSignerForChainID(tx.ChainId()), tx)
+		if err != nil {
+			return nil, err
+		}
+		var receipt *types.Receipt
+		for _, r := range n.rules {
+			if r.Transfer == nil || !r.matchTransfer(from, *tx.To(), tx.Value()) {
+				continue
+			}
+			// 只对匹配的交易取收据，失败的交易没有转出 ETH
+			if receipt == nil {
+				if receipt, err = n.client.TransactionReceipt(ctx, tx.Hash()); err != nil {
+					return nil, err
+				}
+			}
+			if receipt.Status != types.ReceiptStatusSuccessful {
+				break
+			}
+			hash, amount := tx.Hash(), units.FormatEther(tx.Value())
+			found = append(found, &Notification{
+				ID:       fmt.Sprintf("%s:%s", r.Name, hash.Hex()),
+				Rule:     r.Name,
+				Kind:     "transfer",
+				Message:  fmt.Sprintf("%s from %s to %s", amount, n.name(from), n.name(*tx.To())),
+				TxHash:   &hash,
+				Transfer: &Transfer{From: from, To: *tx.To(), Value: tx.Value(), Amount: amount},
+			})
+		}
+	}
+	return found, nil
+}
+
+func (r *rule) matchTransfer(from, to common.Address, value *big.Int) bool {
+	return (r.from == nil || r.from[from]) && (r.to == nil || r.to[to]) && (r.min == nil || value.Cmp(r.min) >= 0)
+}
+
+func (n *Notifier) balances(ctx context.Context, block *types.Block) ([]*Notification, error) {
+	var found []*Notification
+	// 所有余额都读到以后才更新状态，中途出错重试时不会漏掉通知
+	state := make(map[string]bool, len(n.cp.Crossed))
+	for name, crossed := range n.cp.Crossed {
+		state[name] = crossed
+	}
+	for _, r := range n.rules {
+		if r.Balance == nil {
+			continue
+		}
+		balance, err := n.client.BalanceAt(ctx, r.address, block.Number())
+		if err != nil {
+			return nil, err
+		}
+		below := r.below != nil && balance.Cmp(r.below) < 0
+		above := r.above != nil && balance.Cmp(r.above) > 0
+		crossed := below || above
+		if crossed == state[r.Name] {
+			continue
+		}
+		state[r.Name] = crossed
+		if !crossed {
+			continue
+		}
+		change := &BalanceChange{Address: r.address, Balance: balance, Amount: units.FormatEther(balance), Below: below}
+		word := "above"
+		if change.Threshold = r.above; below {
+			word, change.Threshold = "below", r.below
+		}
+		found = append(found, &Notification{
+			ID:      fmt.Sprintf("%s:%d", r.Name, block.NumberU64()),
+			Rule:    r.Name,
+			Kind:    "balance",
+			Message: fmt.Sprintf("balance of %s is %s, %s %s", n.name(r.address), change.Amount, word, units.FormatEther(change.Threshold)),
+			Balance: change,
+		})
+	}
+	n.cp.Crossed = state
+	return found, nil
+}
+
+// fire 并发执行所有通知的动作并等待结束，失败只记录日志，不影响后续区块
+func (n *Notifier) fire(ctx context.Context, found []*Notification) {
+	var wg sync.WaitGroup
+	for _, note := range found {
+		if n.opts.DryRun {
+			if n.opts.Print != nil {
+				n.opts.Print(note)
+			}
+			continue
+		}
+		body, err := json.Marshal(note)
+		if err != nil {
+			log.Printf("%s: %v", note.Rule, err)
+			continue
+		}
+		for _, a := range n.rule(note.Rule).Actions {
+			wg.Add(1)
+			go func(note *Notification, a Action) {
+				defer wg.Done()
+				timeout := a.Timeout
+				if timeout <= 0 {
+					timeout = defaultTimeout
+				}
+				var err error
+				if a.Webhook != nil {
+					err = a.Webhook.deliver(ctx, note, body, timeout)
+				} else {
+					err = run(ctx, a.Command, note, body, timeout)
+				}
+				if err != nil {
+					log.Printf("%s: %s: %v", note.Rule, note.ID, err)
+				}
+			}(note, a)
+		}
+		log.Printf("%s: %s", note.Rule, note.Message)
+	}
+	wg.Wait()
+}
+
+func (n *Notifier) rule(name string) *rule {
+	for _, r := range n.rules {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// save 先写临时文件再改名，进程中途退出也不会留下写了一半的检查点
+func (n *Notifier) save() error {
+	if n.opts.Checkpoint == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(n.cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(n.opts.Checkpoint), filepath.Base(n.opts.Checkpoint)+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), n.opts.Checkpoint)
+}
+
+func (n *Notifier) name(addr common.Address) string {
+	if label := n.opts.Label(addr); label != "" {
+		return label
+	}
+	return addr.Hex()
+}
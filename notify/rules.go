@@ -0,0 +1,303 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gopkg.in/yaml.v3"
+
+	"yunlabs.com/goethereumbook/registry"
+	"yunlabs.com/goethereumbook/units"
+)
+
+// Config is the rule file.
+//
+//	confirmations: 2
+//	rules:
+//	  - name: mtk-transfers
+//	    event:
+//	      contracts: [token:MTK]
+//	      event: ERC20.Transfer
+//	      where: {value: ">=1000000000000000000000"}
+//	    actions:
+//	      - webhook: {url: http://localhost:9000/hook, secret: $HOOK_SECRET}
+//	  - name: whale
+//	    transfer: {min: "100 ether"}
+//	    actions:
+//	      - command: echo "$NOTIFY_MESSAGE" >> alerts.log
+//	  - name: faucet-low
+//	    balance: {address: "@faucet", below: "1 ether"}
+//	    actions:
+//	      - command: ./refill.sh
+type Config struct {
+	// Confirmations 是区块之上还要有多少个区块才处理它。通知发出去就收不回来，重组多的链上应该设大一些
+	Confirmations uint64 `yaml:"confirmations"`
+	Rules         []Rule `yaml:"rules"`
+}
+
+// Rule fires its actions for every match of exactly one of Event, Transfer
+// or Balance.
+type Rule struct {
+	Name     string         `yaml:"name"`
+	Event    *EventMatch    `yaml:"event,omitempty"`
+	Transfer *TransferMatch `yaml:"transfer,omitempty"`
+	Balance  *BalanceMatch  `yaml:"balance,omitempty"`
+	Actions  []Action       `yaml:"actions"`
+}
+
+// EventMatch matches logs of the contracts (any contract when empty) with
+// the event, a signature or the name of a registered event. Where compares
+// decoded arguments by name: a plain value must be equal, addresses also
+// given as address book names; >, >=, < and <= compare integers, such as
+// token amounts in their smallest unit.
+type EventMatch struct {
+	Contracts []string          `yaml:"contracts,omitempty"`
+	Event     string            `yaml:"event,omitempty"`
+	Where     map[string]string `yaml:"where,omitempty"`
+}
+
+// TransferMatch matches transactions sending at least Min, optionally only
+// from or to the given addresses. Like every amount of the CLI, Min is in wei
+// unless a unit such as ether or gwei is given. Internal transfers are not
+// seen.
+type TransferMatch struct {
+	From []string `yaml:"from,omitempty"`
+	To   []string `yaml:"to,omitempty"`
+	Min  string   `yaml:"min,omitempty"`
+}
+
+// BalanceMatch fires when the ETH balance of Address drops below Below or
+// rises above Above, once per crossing. Amounts are parsed like
+// TransferMatch.Min.
+type BalanceMatch struct {
+	Address string `yaml:"address"`
+	Below   string `yaml:"below,omitempty"`
+	Above   string `yaml:"above,omitempty"`
+}
+
+// Action is a webhook or a shell command.
+type Action struct {
+	Webhook *Webhook `yaml:"webhook,omitempty"`
+	// Command 用 sh -c 执行，通知的 JSON 从标准输入传入，常用字段也放在 NOTIFY_* 环境变量里
+	Command string        `yaml:"command,omitempty"`
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+}
+
+// Resolver turns an address or an address book name into an address.
+type Resolver func(string) (common.Address, error)
+
+// LoadConfig reads a rule file. Environment variables ($VAR) in webhook
+// urls, secrets and headers are expanded, so secrets need not be stored in
+// the file. A secret expanding to an empty string is rejected when the rules
+// are compiled.
+func LoadConfig(file string) (*Config, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	for _, r := range cfg.Rules {
+		for _, a := range r.Actions {
+			if w := a.Webhook; w != nil {
+				w.rawSecret = w.Secret
+				w.URL, w.Secret = os.ExpandEnv(w.URL), os.ExpandEnv(w.Secret)
+				for k, v := range w.Headers {
+					w.Headers[k] = os.ExpandEnv(v)
+				}
+			}
+		}
+	}
+	return &cfg, nil
+}
+
+// rule 是检查过、地址和金额都解析好的规则
+type rule struct {
+	Rule
+
+	contracts map[common.Address]bool
+	topic     *common.Hash
+	where     map[string]condition
+
+	from, to map[common.Address]bool
+	min      *big.Int
+
+	address      common.Address
+	below, above *big.Int
+}
+
+type condition struct {
+	op    string // "" 表示相等
+	value string
+	n     *big.Int
+}
+
+var ops = []string{">=", "<=", ">", "<"}
+
+func compile(cfg *Config, reg *registry.Registry, resolve Resolver) ([]*rule, error) {
+	var rules []*rule
+	names := make(map[string]bool)
+	for i, r := range cfg.Rules {
+		if r.Name == "" {
+			r.Name = fmt.Sprintf("rule%d", i+1)
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("duplicate rule name %s", r.Name)
+		}
+		names[r.Name] = true
+		c, err := compileRule(r, reg, resolve)
+		if err != nil {
+			return nil, fmt.Errorf("rule %s: %v", r.Name, err)
+		}
+		rules = append(rules, c)
+	}
+	return rules, nil
+}
+
+func compileRule(r Rule, reg *registry.Registry, resolve Resolver) (*rule, error) {
+	c := &rule{Rule: r}
+	kinds := 0
+	for _, set := range []bool{r.Event != nil, r.Transfer != nil, r.Balance != nil} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return nil, errors.New("needs exactly one of event, transfer or balance")
+	}
+	if len(r.Actions) == 0 {
+		return nil, errors.New("no actions")
+	}
+	for _, a := range r.Actions {
+		if (a.Webhook == nil) == (a.Command == "") {
+			return nil, errors.New("an action needs exactly one of webhook or command")
+		}
+		if a.Webhook != nil && a.Webhook.URL == "" {
+			return nil, errors.New("webhook without url")
+		}
+		// 配置了 secret 但环境变量没设置时展开成空串，不签名就发出去不会有任何提示
+		if a.Webhook != nil && a.Webhook.rawSecret != "" && a.Webhook.Secret == "" {
+			return nil, fmt.Errorf("webhook secret %s expands to an empty string", a.Webhook.rawSecret)
+		}
+	}
+
+	var err error
+	switch {
+	case r.Event != nil:
+		if c.contracts, err = addressSet(r.Event.Contracts, resolve); err != nil {
+			return nil, err
+		}
+		if r.Event.Event != "" {
+			topic, err := reg.EventTopic(r.Event.Event)
+			if err != nil {
+				return nil, err
+			}
+			c.topic = &topic
+		}
+		if len(r.Event.Where) > 0 && c.topic == nil {
+			return nil, errors.New("where needs the event")
+		}
+		c.where = make(map[string]condition)
+		for name, s := range r.Event.Where {
+			if c.where[name], err = parseCondition(s, resolve); err != nil {
+				return nil, fmt.Errorf("where %s: %v", name, err)
+			}
+		}
+	case r.Transfer != nil:
+		if c.from, err = addressSet(r.Transfer.From, resolve); err != nil {
+			return nil, err
+		}
+		if c.to, err = addressSet(r.Transfer.To, resolve); err != nil {
+			return nil, err
+		}
+		if c.min, err = ether(r.Transfer.Min); err != nil {
+			return nil, err
+		}
+	case r.Balance != nil:
+		if c.address, err = resolve(r.Balance.Address); err != nil {
+			return nil, err
+		}
+		if c.below, err = ether(r.Balance.Below); err != nil {
+			return nil, err
+		}
+		if c.above, err = ether(r.Balance.Above); err != nil {
+			return nil, err
+		}
+		if c.below == nil && c.above == nil {
+			return nil, errors.New("balance needs below or above")
+		}
+	}
+	return c, nil
+}
+
+func addressSet(list []string, resolve Resolver) (map[common.Address]bool, error) {
+	if len(list) == 0 {
+		return nil, nil
+	}
+	set := make(map[common.Address]bool)
+	for _, s := range list {
+		addr, err := resolve(s)
+		if err != nil {
+			return nil, err
+		}
+		set[addr] = true
+	}
+	return set, nil
+}
+
+// ether 解析 ETH 金额，和命令行参数一样不带单位时是 wei("0.5 ether"、"30 gwei")，空字符串表示没有限制
+func ether(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return units.ParseEther(s)
+}
+
+// parseCondition 解析 where 的值：带比较符号的是整数，其他按相等比较，能解析成地址的先换成地址
+func parseCondition(s string, resolve Resolver) (condition, error) {
+	s = strings.TrimSpace(s)
+	for _, op := range ops {
+		if strings.HasPrefix(s, op) {
+			n, ok := new(big.Int).SetString(strings.TrimSpace(s[len(op):]), 0)
+			if !ok {
+				return condition{}, fmt.Errorf("%s needs an integer", op)
+			}
+			return condition{op: op, n: n}, nil
+		}
+	}
+	if addr, err := resolve(s); err == nil {
+		return condition{value: addr.Hex()}, nil
+	}
+	return condition{value: s}, nil
+}
+
+// match 比较解码后的参数值
+func (c condition) match(v interface{}) bool {
+	if c.op == "" {
+		return strings.EqualFold(registry.FormatValue(v), c.value)
+	}
+	n, ok := v.(*big.Int)
+	if !ok {
+		var set bool
+		if n, set = new(big.Int).SetString(registry.FormatValue(v), 10); !set {
+			return false
+		}
+	}
+	cmp := n.Cmp(c.n)
+	switch c.op {
+	case ">=":
+		return cmp >= 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	}
+	return cmp < 0
+}
@@ -0,0 +1,294 @@
+// Package output 把命令的结果(区块、交易、收据、余额、代币信息、事件)渲染成对齐的表格、JSON、YAML 或 CSV，
+// 脚本和流水线可以直接解析命令行的输出。表格和 CSV 的列取自结构体的 json 标签，嵌套结构体展开成 a.b 形式的列。
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"gopkg.in/yaml.v3"
+)
+
+// Format is an output format.
+type Format string
+
+const (
+	Table Format = "table"
+	JSON  Format = "json"
+	YAML  Format = "yaml"
+	CSV   Format = "csv"
+)
+
+// Formats lists the supported formats.
+var Formats = []Format{Table, JSON, YAML, CSV}
+
+// ParseFormat checks that s names a supported format.
+func ParseFormat(s string) (Format, error) {
+	for _, f := range Formats {
+		if strings.EqualFold(s, string(f)) {
+			return f, nil
+		}
+	}
+	return "", fmt.Errorf("unknown output format %q, want one of table, json, yaml, csv", s)
+}
+
+// Write renders v, a struct or a slice of structs, in format f.
+func Write(w io.Writer, f Format, v interface{}) error {
+	switch f {
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case YAML:
+		return writeYAML(w, v)
+	case Table:
+		return writeTable(w, v)
+	case CSV:
+		return writeCSV(w, v)
+	}
+	return fmt.Errorf("unknown output format %q", f)
+}
+
+// writeYAML 先编码成 JSON 再转成 YAML，字段名、大整数和地址的写法与 JSON 输出完全一致
+func writeYAML(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	plain(&node)
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// plain 去掉从 JSON 继承的流式和引号风格，输出块风格的 YAML
+func plain(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		plain(c)
+	}
+}
+
+func writeTable(w io.Writer, v interface{}) error {
+	rows, single, err := records(v)
+	if err != nil {
+		return err
+	}
+	cols := columns(rows.Type().Elem(), "")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	// 单个结果每行一个字段，列表每行一条记录
+	if single {
+		for _, c := range cols {
+			fmt.Fprintf(tw, "%s:\t%s\n", c.name, c.cell(rows.Index(0), " "))
+		}
+		return tw.Flush()
+	}
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = strings.ToUpper(c.name)
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+	for i := 0; i < rows.Len(); i++ {
+		cells := make([]string, len(cols))
+		for j, c := range cols {
+			cells[j] = c.cell(rows.Index(i), " ")
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+func writeCSV(w io.Writer, v interface{}) error {
+	rows, _, err := records(v)
+	if err != nil {
+		return err
+	}
+	cols := columns(rows.Type().Elem(), "")
+	cw := csv.NewWriter(w)
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = c.name
+	}
+	cw.Write(header)
+	for i := 0; i < rows.Len(); i++ {
+		record := make([]string, len(cols))
+		for j, c := range cols {
+			record[j] = c.cell(rows.Index(i), ";")
+		}
+		cw.Write(record)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// records 把单个结构体或结构体切片统一成切片，single 表示传入的是单个结构体
+func records(v interface{}) (reflect.Value, bool, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return reflect.Value{}, false, fmt.Errorf("nothing to render")
+		}
+		rv = rv.Elem()
+	}
+	switch {
+	case rv.Kind() == reflect.Struct:
+		s := reflect.MakeSlice(reflect.SliceOf(rv.Type()), 1, 1)
+		s.Index(0).Set(rv)
+		return s, true, nil
+	case (rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array) && structType(rv.Type().Elem()) != nil:
+		return rv, false, nil
+	}
+	return reflect.Value{}, false, fmt.Errorf("cannot render %T as a table", v)
+}
+
+// column 是表格或 CSV 的一列，path 是从记录到字段的结构体路径(途经的指针允许为 nil)
+type column struct {
+	name string
+	path []int
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+)
+
+// structType 返回需要展开成多列的结构体类型，t 不是这样的结构体时返回 nil
+func structType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		if t.Implements(stringerType) {
+			return nil
+		}
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || t == timeType || t.Implements(stringerType) || reflect.PtrTo(t).Implements(stringerType) {
+		return nil
+	}
+	return t
+}
+
+func columns(t reflect.Type, prefix string) []column {
+	t = structType(t)
+	if t == nil {
+		return nil
+	}
+	var cols []column
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		// 没有 json 标签的嵌入结构体直接展开，不加前缀
+		if f.Anonymous && name == "" && structType(f.Type) != nil {
+			for _, c := range columns(f.Type, prefix) {
+				cols = append(cols, column{c.name, append([]int{i}, c.path...)})
+			}
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		if structType(f.Type) != nil {
+			for _, c := range columns(f.Type, prefix+name+".") {
+				cols = append(cols, column{c.name, append([]int{i}, c.path...)})
+			}
+			continue
+		}
+		cols = append(cols, column{prefix + name, []int{i}})
+	}
+	return cols
+}
+
+func (c column) cell(record reflect.Value, sep string) string {
+	v := record
+	for _, i := range c.path {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return ""
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	return cell(v, sep)
+}
+
+// cell 把一个字段格式化成单元格文本：地址、哈希、大整数等用 String()，
+// 标量切片用 sep 连接，map 按键排序写成 k=v，其余复杂值写成紧凑的 JSON
+func cell(v reflect.Value, sep string) string {
+	if !v.IsValid() {
+		return ""
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		if v.IsNil() {
+			return ""
+		}
+	}
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+	if v.Type().Implements(stringerType) {
+		return v.Interface().(fmt.Stringer).String()
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(stringerType) {
+		return v.Addr().Interface().(fmt.Stringer).String()
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return cell(v.Elem(), sep)
+	case reflect.Slice, reflect.Array:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, v.Len())
+			reflect.Copy(reflect.ValueOf(b), v)
+			return hexutil.Encode(b)
+		}
+		if structType(v.Type().Elem()) != nil {
+			break
+		}
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = cell(v.Index(i), sep)
+		}
+		return strings.Join(items, sep)
+	case reflect.Map:
+		items := make([]string, 0, v.Len())
+		for _, k := range v.MapKeys() {
+			items = append(items, cell(k, sep)+"="+cell(v.MapIndex(k), sep))
+		}
+		sort.Strings(items)
+		return strings.Join(items, sep)
+	case reflect.Struct:
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return fmt.Sprint(v.Interface())
+	}
+	return string(data)
+}
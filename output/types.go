@@ -0,0 +1,184 @@
+package output
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+
+	"yunlabs.com/goethereumbook/trace"
+	"yunlabs.com/goethereumbook/units"
+)
+
+// Block is a block header with its transaction count.
+type Block struct {
+	Number       uint64         `json:"number"`
+	Hash         common.Hash    `json:"hash"`
+	ParentHash   common.Hash    `json:"parentHash"`
+	Timestamp    uint64         `json:"timestamp"`
+	Time         time.Time      `json:"time"`
+	Miner        common.Address `json:"miner"`
+	MinerLabel   string         `json:"minerLabel,omitempty"`
+	Difficulty   *big.Int       `json:"difficulty"`
+	GasUsed      uint64         `json:"gasUsed"`
+	GasLimit     uint64         `json:"gasLimit"`
+	BaseFee      *big.Int       `json:"baseFeePerGas,omitempty"`
+	Transactions int            `json:"transactions"`
+}
+
+// NewBlock describes a header of a block with txs transactions.
+func NewBlock(h *types.Header, txs int) Block {
+	return Block{
+		Number:       h.Number.Uint64(),
+		Hash:         h.Hash(),
+		ParentHash:   h.ParentHash,
+		Timestamp:    h.Time,
+		Time:         time.Unix(int64(h.Time), 0).UTC(),
+		Miner:        h.Coinbase,
+		Difficulty:   h.Difficulty,
+		GasUsed:      h.GasUsed,
+		GasLimit:     h.GasLimit,
+		BaseFee:      h.BaseFee,
+		Transactions: txs,
+	}
+}
+
+// Tx is a transaction, with its receipt once it is mined.
+type Tx struct {
+	Hash        common.Hash      `json:"hash"`
+	BlockNumber uint64           `json:"blockNumber,omitempty"`
+	Index       uint             `json:"transactionIndex"`
+	Type        uint8            `json:"type"`
+	From        common.Address   `json:"from"`
+	FromLabel   string           `json:"fromLabel,omitempty"`
+	To          *common.Address  `json:"to"`
+	ToLabel     string           `json:"toLabel,omitempty"`
+	Value       *big.Int         `json:"value"`
+	Ether       string           `json:"ether"`
+	Nonce       uint64           `json:"nonce"`
+	Gas         uint64           `json:"gas"`
+	GasPrice    *big.Int         `json:"gasPrice"`
+	Input       hexutil.Bytes    `json:"input"`
+	Receipt     *Receipt         `json:"receipt,omitempty"`
+	Internal    []trace.Transfer `json:"internalTransfers,omitempty"`
+}
+
+// NewTx describes tx sent by from. Block fields, the receipt and internal
+// transfers are filled in by the caller when known.
+func NewTx(tx *types.Transaction, from common.Address) Tx {
+	return Tx{
+		Hash:     tx.Hash(),
+		Type:     tx.Type(),
+		From:     from,
+		To:       tx.To(),
+		Value:    tx.Value(),
+		Ether:    units.Format(tx.Value(), units.Ether.Decimals, units.Exact),
+		Nonce:    tx.Nonce(),
+		Gas:      tx.Gas(),
+		GasPrice: tx.GasPrice(),
+		Input:    tx.Data(),
+	}
+}
+
+// Receipt is the outcome of a mined transaction.
+type Receipt struct {
+	Status            uint64          `json:"status"`
+	GasUsed           uint64          `json:"gasUsed"`
+	CumulativeGasUsed uint64          `json:"cumulativeGasUsed"`
+	EffectiveGasPrice *big.Int        `json:"effectiveGasPrice,omitempty"`
+	ContractAddress   *common.Address `json:"contractAddress,omitempty"`
+	Logs              []Event         `json:"logs"`
+}
+
+// NewReceipt describes r, its logs without decoding.
+func NewReceipt(r *types.Receipt) *Receipt {
+	out := &Receipt{
+		Status:            r.Status,
+		GasUsed:           r.GasUsed,
+		CumulativeGasUsed: r.CumulativeGasUsed,
+		EffectiveGasPrice: r.EffectiveGasPrice,
+		Logs:              make([]Event, len(r.Logs)),
+	}
+	if r.ContractAddress != (common.Address{}) {
+		addr := r.ContractAddress
+		out.ContractAddress = &addr
+	}
+	for i, l := range r.Logs {
+		out.Logs[i] = NewEvent(l)
+	}
+	return out
+}
+
+// Balance is the amount of one asset held by an address. Raw is in the
+// smallest unit, Amount is the same value as an exact decimal.
+type Balance struct {
+	Address  common.Address  `json:"address"`
+	Label    string          `json:"label,omitempty"`
+	Block    string          `json:"block,omitempty"`
+	Asset    string          `json:"asset"`
+	Token    *common.Address `json:"token,omitempty"`
+	Decimals uint8           `json:"decimals"`
+	Raw      *big.Int        `json:"raw"`
+	Amount   string          `json:"amount"`
+}
+
+// NewBalance describes raw units of asset u held by addr. Token is nil for
+// ETH.
+func NewBalance(addr common.Address, u units.Unit, token *common.Address, raw *big.Int) Balance {
+	if raw == nil {
+		raw = new(big.Int)
+	}
+	return Balance{
+		Address:  addr,
+		Asset:    u.Name,
+		Token:    token,
+		Decimals: u.Decimals,
+		Raw:      raw,
+		Amount:   units.Format(raw, u.Decimals, units.Exact),
+	}
+}
+
+// TokenInfo is the metadata of an ERC20 token.
+type TokenInfo struct {
+	Address     common.Address `json:"address"`
+	Name        string         `json:"name"`
+	Symbol      string         `json:"symbol"`
+	Decimals    uint8          `json:"decimals"`
+	TotalSupply *big.Int       `json:"totalSupply,omitempty"`
+}
+
+// Unit returns the token's denomination for units.Format.
+func (t TokenInfo) Unit() units.Unit {
+	return units.Unit{Name: t.Symbol, Decimals: t.Decimals}
+}
+
+// Event is a contract log, with its name and arguments when it could be
+// decoded against a known ABI.
+type Event struct {
+	Address     common.Address         `json:"address"`
+	BlockNumber uint64                 `json:"blockNumber"`
+	TxHash      common.Hash            `json:"transactionHash"`
+	LogIndex    uint                   `json:"logIndex"`
+	Name        string                 `json:"event,omitempty"`
+	Contract    string                 `json:"contract,omitempty"`
+	Signature   string                 `json:"signature,omitempty"`
+	Args        map[string]interface{} `json:"args,omitempty"`
+	Topics      []common.Hash          `json:"topics"`
+	Data        hexutil.Bytes          `json:"data"`
+	Removed     bool                   `json:"removed,omitempty"`
+}
+
+// NewEvent describes l without decoding it.
+func NewEvent(l *types.Log) Event {
+	return Event{
+		Address:     l.Address,
+		BlockNumber: l.BlockNumber,
+		TxHash:      l.TxHash,
+		LogIndex:    l.Index,
+		Topics:      l.Topics,
+		Data:        l.Data,
+		Removed:     l.Removed,
+	}
+}
@@ -0,0 +1,151 @@
+// Package proof 在本地校验 eth_getProof 返回的账户和存储证明。
+// 只要区块头的 stateRoot 可信，就能判断不可信 RPC 给出的余额、nonce 和存储值是否属实。
+package proof
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/gethclient"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// Account is the account state proven against a state root.
+type Account struct {
+	Address     common.Address
+	Nonce       uint64
+	Balance     *big.Int
+	StorageRoot common.Hash
+	CodeHash    common.Hash
+	Storage     []Slot
+}
+
+// Slot is a storage slot proven against the account's storage root.
+type Slot struct {
+	Key   common.Hash
+	Value common.Hash
+}
+
+// Verify checks res, the answer to eth_getProof for addr and keys, against
+// stateRoot and returns the proven account state. It fails if any proof is
+// invalid, the values claimed by the node differ from the proven ones, or
+// the node answered for another account or other storage keys.
+func Verify(stateRoot common.Hash, addr common.Address, keys []common.Hash, res *gethclient.AccountResult) (*Account, error) {
+	// 不可信的节点可能给出另一个账户的有效证明，或者漏掉部分存储位置，证明本身校验不出来
+	if res.Address != addr {
+		return nil, fmt.Errorf("node returned a proof for %s, asked for %s", res.Address.Hex(), addr.Hex())
+	}
+	if len(res.StorageProof) != len(keys) {
+		return nil, fmt.Errorf("node returned %d storage proofs, asked for %d", len(res.StorageProof), len(keys))
+	}
+	for i, sp := range res.StorageProof {
+		if common.HexToHash(sp.Key) != keys[i] {
+			return nil, fmt.Errorf("storage proof %d is for key %s, asked for %x", i, sp.Key, keys[i])
+		}
+	}
+
+	db, err := proofDB(res.AccountProof)
+	if err != nil {
+		return nil, err
+	}
+	enc, err := trie.VerifyProof(stateRoot, crypto.Keccak256(res.Address.Bytes()), db)
+	if err != nil {
+		return nil, fmt.Errorf("account proof: %v", err)
+	}
+
+	// 证明有效但值为空，说明账户不存在，按空账户处理
+	state := types.NewEmptyStateAccount()
+	if enc != nil {
+		if err := rlp.DecodeBytes(enc, state); err != nil {
+			return nil, fmt.Errorf("account rlp: %v", err)
+		}
+	}
+	acct := &Account{
+		Address:     res.Address,
+		Nonce:       state.Nonce,
+		Balance:     state.Balance,
+		StorageRoot: state.Root,
+		CodeHash:    common.BytesToHash(state.CodeHash),
+	}
+	if err := checkClaims(acct, res); err != nil {
+		return nil, err
+	}
+
+	for _, sp := range res.StorageProof {
+		key := common.HexToHash(sp.Key)
+		db, err := proofDB(sp.Proof)
+		if err != nil {
+			return nil, err
+		}
+		enc, err := trie.VerifyProof(acct.StorageRoot, crypto.Keccak256(key.Bytes()), db)
+		if err != nil {
+			return nil, fmt.Errorf("storage proof %x: %v", key, err)
+		}
+		// 存储树中的值是去掉前导零后再做 RLP 编码的
+		var value []byte
+		if enc != nil {
+			if _, value, _, err = rlp.Split(enc); err != nil {
+				return nil, fmt.Errorf("storage rlp %x: %v", key, err)
+			}
+		}
+		slot := Slot{Key: key, Value: common.BytesToHash(value)}
+		if sp.Value != nil && sp.Value.Cmp(slot.Value.Big()) != 0 {
+			return nil, fmt.Errorf("storage %x: node claims %#x, proof says %#x", key, sp.Value, slot.Value.Big())
+		}
+		acct.Storage = append(acct.Storage, slot)
+	}
+	return acct, nil
+}
+
+func checkClaims(acct *Account, res *gethclient.AccountResult) error {
+	var errs []string
+	if res.Balance != nil && res.Balance.Cmp(acct.Balance) != 0 {
+		errs = append(errs, fmt.Sprintf("balance: node claims %v, proof says %v", res.Balance, acct.Balance))
+	}
+	if res.Nonce != acct.Nonce {
+		errs = append(errs, fmt.Sprintf("nonce: node claims %d, proof says %d", res.Nonce, acct.Nonce))
+	}
+	// 不存在的账户，节点可能返回全零的 codeHash/storageHash
+	if res.CodeHash != acct.CodeHash && !(EmptyCode(res.CodeHash) && EmptyCode(acct.CodeHash)) {
+		errs = append(errs, fmt.Sprintf("codeHash: node claims %x, proof says %x", res.CodeHash, acct.CodeHash))
+	}
+	if res.StorageHash != acct.StorageRoot && !(emptyRoot(res.StorageHash) && emptyRoot(acct.StorageRoot)) {
+		errs = append(errs, fmt.Sprintf("storageHash: node claims %x, proof says %x", res.StorageHash, acct.StorageRoot))
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// proofDB 把证明中的节点按 keccak256(node) => node 放入内存数据库，供 trie.VerifyProof 查找
+func proofDB(nodes []string) (*memorydb.Database, error) {
+	db := memorydb.New()
+	for _, node := range nodes {
+		enc, err := hexutil.Decode(node)
+		if err != nil {
+			return nil, fmt.Errorf("proof node %q: %v", node, err)
+		}
+		if err := db.Put(crypto.Keccak256(enc), enc); err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+// EmptyCode reports whether codeHash belongs to an account without code.
+func EmptyCode(codeHash common.Hash) bool {
+	return codeHash == types.EmptyCodeHash || codeHash == (common.Hash{})
+}
+
+func emptyRoot(root common.Hash) bool {
+	return root == types.EmptyRootHash || root == (common.Hash{})
+}
@@ -0,0 +1,404 @@
+// Package registry 收集已知合约的 ABI，按方法选择器解码交易的 calldata，按错误选择器解码 revert 数据，
+// 按 topic0 解码事件日志。
+package registry
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"yunlabs.com/goethereumbook/contracts/multicall"
+	"yunlabs.com/goethereumbook/contracts/store"
+	"yunlabs.com/goethereumbook/contracts/token"
+)
+
+// ErrUnknownSelector is returned when no registered ABI has the method.
+var ErrUnknownSelector = errors.New("unknown method selector")
+
+// ErrUnknownEvent is returned when no registered ABI has an event matching
+// the log's topic0 and number of indexed arguments.
+var ErrUnknownEvent = errors.New("unknown event")
+
+type method struct {
+	contract string
+	method   abi.Method
+}
+
+type customError struct {
+	contract string
+	err      abi.Error
+}
+
+type event struct {
+	contract string
+	event    abi.Event
+}
+
+// Registry maps method and error selectors and event topics to the ABIs
+// that define them.
+type Registry struct {
+	methods map[[4]byte]method
+	errors  map[[4]byte]customError
+	// 同一个签名的事件可能有不同的 indexed 参数(如 ERC20 和 ERC721 的 Transfer)，都保留下来
+	events map[common.Hash][]event
+}
+
+// New returns an empty registry.
+func New() *Registry {
+	return &Registry{
+		methods: make(map[[4]byte]method),
+		errors:  make(map[[4]byte]customError),
+		events:  make(map[common.Hash][]event),
+	}
+}
+
+// Default returns a registry with the ABIs of the bundled bindings.
+func Default() *Registry {
+	r := New()
+	for name, meta := range map[string]interface{ GetAbi() (*abi.ABI, error) }{
+		"Store":      store.StoreMetaData,
+		"ERC20":      token.TokenMetaData,
+		"Multicall3": multicall.Multicall3MetaData,
+	} {
+		parsed, err := meta.GetAbi()
+		if err != nil {
+			panic(err) // 绑定代码自带的 ABI 不应该出错
+		}
+		r.Add(name, parsed)
+	}
+	return r
+}
+
+// Add registers every method, custom error and event of contractABI under
+// the given contract name. Selectors already known are kept, the first
+// registration wins; events are only skipped when an event with the same
+// topic and indexed arguments is known.
+func (r *Registry) Add(name string, contractABI *abi.ABI) {
+	for _, m := range contractABI.Methods {
+		var id [4]byte
+		copy(id[:], m.ID)
+		if _, ok := r.methods[id]; !ok {
+			r.methods[id] = method{contract: name, method: m}
+		}
+	}
+	for _, e := range contractABI.Errors {
+		var id [4]byte
+		copy(id[:], e.ID[:4])
+		if _, ok := r.errors[id]; !ok {
+			r.errors[id] = customError{contract: name, err: e}
+		}
+	}
+	for _, e := range contractABI.Events {
+		if e.Anonymous {
+			continue
+		}
+		known := false
+		for _, k := range r.events[e.ID] {
+			known = known || sameIndexed(k.event, e)
+		}
+		if !known {
+			r.events[e.ID] = append(r.events[e.ID], event{contract: name, event: e})
+		}
+	}
+}
+
+// sameIndexed 判断两个同签名的事件哪些参数是 indexed 是否一致
+func sameIndexed(a, b abi.Event) bool {
+	for i := range a.Inputs {
+		if a.Inputs[i].Indexed != b.Inputs[i].Indexed {
+			return false
+		}
+	}
+	return true
+}
+
+// LoadDir registers every *.abi file in dir, named after the file.
+func (r *Registry) LoadDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.abi"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if err := r.LoadFile(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFile registers the ABI in file, named after the file.
+func (r *Registry) LoadFile(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	parsed, err := abi.JSON(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	r.Add(strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)), &parsed)
+	return nil
+}
+
+// Call is a decoded contract call.
+type Call struct {
+	Contract string
+	Method   abi.Method
+	Args     []interface{}
+}
+
+// DecodeCall decodes tx calldata against the registered methods.
+func (r *Registry) DecodeCall(data []byte) (*Call, error) {
+	if len(data) < 4 {
+		return nil, ErrUnknownSelector
+	}
+	var id [4]byte
+	copy(id[:], data[:4])
+	m, ok := r.methods[id]
+	if !ok {
+		return nil, ErrUnknownSelector
+	}
+	args, err := m.method.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, fmt.Errorf("%s.%s: %v", m.contract, m.method.Name, err)
+	}
+	return &Call{Contract: m.contract, Method: m.method, Args: args}, nil
+}
+
+// Returns decodes the return data of the call, an empty string when there
+// are no outputs or the data does not match.
+func (c *Call) Returns(output []byte) string {
+	if len(c.Method.Outputs) == 0 || len(output) == 0 {
+		return ""
+	}
+	values, err := c.Method.Outputs.Unpack(output)
+	if err != nil {
+		return ""
+	}
+	return FormatArgs(c.Method.Outputs, values)
+}
+
+// panicReasons 是 Panic(uint256) 的错误码，见 Solidity 文档
+var panicReasons = map[uint64]string{
+	0x01: "assert failed",
+	0x11: "arithmetic overflow or underflow",
+	0x12: "division by zero",
+	0x21: "invalid enum value",
+	0x22: "invalid storage byte array",
+	0x31: "pop on empty array",
+	0x32: "array index out of bounds",
+	0x41: "out of memory",
+	0x51: "call to uninitialized function",
+}
+
+var panicSelector = crypto.Keccak256([]byte("Panic(uint256)"))[:4]
+
+// DecodeRevert decodes revert data: Error(string) reasons, Panic(uint256)
+// codes and custom errors of the registered ABIs. It returns false when the
+// data is none of these.
+func (r *Registry) DecodeRevert(data []byte) (string, bool) {
+	if reason, err := abi.UnpackRevert(data); err == nil {
+		return reason, true
+	}
+	if len(data) < 4 {
+		return "", false
+	}
+	if bytes.Equal(data[:4], panicSelector) && len(data) == 36 {
+		code := new(big.Int).SetBytes(data[4:])
+		if reason, ok := panicReasons[code.Uint64()]; ok && code.IsUint64() {
+			return fmt.Sprintf("panic: %s (0x%x)", reason, code), true
+		}
+		return fmt.Sprintf("panic: 0x%x", code), true
+	}
+	var id [4]byte
+	copy(id[:], data[:4])
+	e, ok := r.errors[id]
+	if !ok {
+		return "", false
+	}
+	values, err := e.err.Inputs.Unpack(data[4:])
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%s.%s(%s)", e.contract, e.err.Name, FormatArgs(e.err.Inputs, values)), true
+}
+
+// Log is a decoded event log. Args follow the order of Event.Inputs;
+// indexed strings, bytes and arrays are only known by their hash.
+type Log struct {
+	Contract string
+	Event    abi.Event
+	Args     []interface{}
+}
+
+// DecodeLog decodes l against the registered events with the same topic0
+// and number of indexed arguments.
+func (r *Registry) DecodeLog(l *types.Log) (*Log, error) {
+	if len(l.Topics) == 0 {
+		return nil, ErrUnknownEvent
+	}
+	var lastErr error = ErrUnknownEvent
+	for _, e := range r.events[l.Topics[0]] {
+		args, err := unpackLog(e.event, l)
+		if err == nil {
+			return &Log{Contract: e.contract, Event: e.event, Args: args}, nil
+		}
+		if err != errTopicCount {
+			lastErr = fmt.Errorf("%s.%s: %v", e.contract, e.event.Name, err)
+		}
+	}
+	return nil, lastErr
+}
+
+// EventTopic returns the topic0 of an event given by its signature, such as
+// Transfer(address,address,uint256), or by the name of a registered event,
+// Transfer or ERC20.Transfer.
+func (r *Registry) EventTopic(name string) (common.Hash, error) {
+	if strings.Contains(name, "(") {
+		return crypto.Keccak256Hash([]byte(strings.ReplaceAll(name, " ", ""))), nil
+	}
+	contract, eventName := "", name
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		contract, eventName = name[:i], name[i+1:]
+	}
+	var found []common.Hash
+	for id, events := range r.events {
+		for _, e := range events {
+			if e.event.Name == eventName && (contract == "" || e.contract == contract) {
+				found = append(found, id)
+				break
+			}
+		}
+	}
+	switch len(found) {
+	case 0:
+		return common.Hash{}, fmt.Errorf("%w: %s", ErrUnknownEvent, name)
+	case 1:
+		return found[0], nil
+	}
+	// 不同合约里同名但参数不同的事件，要用签名区分
+	return common.Hash{}, fmt.Errorf("event %s is ambiguous, give its signature", name)
+}
+
+var errTopicCount = errors.New("topic count mismatch")
+
+func unpackLog(e abi.Event, l *types.Log) ([]interface{}, error) {
+	indexed := 0
+	for _, arg := range e.Inputs {
+		if arg.Indexed {
+			indexed++
+		}
+	}
+	if indexed != len(l.Topics)-1 {
+		return nil, errTopicCount
+	}
+	data, err := e.Inputs.NonIndexed().Unpack(l.Data)
+	if err != nil {
+		return nil, err
+	}
+	args := make([]interface{}, 0, len(e.Inputs))
+	topic := 1
+	for _, arg := range e.Inputs {
+		if !arg.Indexed {
+			args, data = append(args, data[0]), data[1:]
+			continue
+		}
+		// 逐个参数解析 topic，参数没有名字时也不会互相覆盖
+		arg.Name = "v"
+		m := make(map[string]interface{})
+		if err := abi.ParseTopicsIntoMap(m, abi.Arguments{arg}, l.Topics[topic:topic+1]); err != nil {
+			return nil, err
+		}
+		args = append(args, m["v"])
+		topic++
+	}
+	return args, nil
+}
+
+// Map returns the arguments by name, arg0, arg1... for unnamed ones.
+func (l *Log) Map() map[string]interface{} {
+	m := make(map[string]interface{}, len(l.Args))
+	for i, v := range l.Args {
+		name := l.Event.Inputs[i].Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		m[name] = v
+	}
+	return m
+}
+
+// String renders the log as Contract.Event(name=value, ...).
+func (l *Log) String() string {
+	return fmt.Sprintf("%s.%s(%s)", l.Contract, l.Event.Name, FormatArgs(l.Event.Inputs, l.Args))
+}
+
+// String renders the call as Contract.method(name=value, ...).
+func (c *Call) String() string {
+	return fmt.Sprintf("%s.%s(%s)", c.Contract, c.Method.Name, FormatArgs(c.Method.Inputs, c.Args))
+}
+
+// FormatArgs renders decoded values as name=value pairs.
+func FormatArgs(args abi.Arguments, values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		name := fmt.Sprintf("arg%d", i)
+		if i < len(args) && args[i].Name != "" {
+			name = args[i].Name
+		}
+		parts[i] = name + "=" + FormatValue(v)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// FormatValue prints fixed byte arrays and byte slices as hex, arrays as
+// [a, b] and tuples as {name: value, ...}, everything else with its default
+// format.
+func FormatValue(v interface{}) string {
+	switch v := v.(type) {
+	case []byte:
+		return hexutil.Encode(v)
+	case fmt.Stringer:
+		return v.String()
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Array, reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return hexutil.Encode(b)
+		}
+		items := make([]string, rv.Len())
+		for i := range items {
+			items[i] = FormatValue(rv.Index(i).Interface())
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case reflect.Struct:
+		// abi 解出的 tuple 是匿名结构体，字段名取自 json 标签(即 ABI 中的名字)
+		items := make([]string, 0, rv.NumField())
+		for i := 0; i < rv.NumField(); i++ {
+			f := rv.Type().Field(i)
+			name := f.Tag.Get("json")
+			if name == "" {
+				name = f.Name
+			}
+			items = append(items, name+": "+FormatValue(rv.Field(i).Interface()))
+		}
+		return "{" + strings.Join(items, ", ") + "}"
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,56 @@
+package signer
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// SignMessage signs msg as an EIP-191 personal message
+// ("\x19Ethereum Signed Message:\n" + len(msg) + msg). The returned
+// signature uses V = 27/28 like personal_sign in wallets.
+func SignMessage(s Signer, msg []byte) ([]byte, error) {
+	sig, err := s.SignHash(accounts.TextHash(msg))
+	if err != nil {
+		return nil, err
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+	return sig, nil
+}
+
+// RecoverMessage returns the address that signed msg. Both V = 27/28 and
+// V = 0/1 signatures are accepted.
+func RecoverMessage(msg, sig []byte) (common.Address, error) {
+	return recoverHash(accounts.TextHash(msg), sig)
+}
+
+// VerifyMessage reports whether sig is a signature of msg by addr.
+func VerifyMessage(addr common.Address, msg, sig []byte) (bool, error) {
+	signer, err := RecoverMessage(msg, sig)
+	if err != nil {
+		return false, err
+	}
+	return signer == addr, nil
+}
+
+// recoverHash 从签名中恢复公钥并得到地址，钱包给出的 V 一般是 27/28，需要先减去 27
+func recoverHash(hash, sig []byte) (common.Address, error) {
+	if len(sig) != crypto.SignatureLength {
+		return common.Address{}, fmt.Errorf("signature must be %d bytes, got %d", crypto.SignatureLength, len(sig))
+	}
+	sig = append([]byte{}, sig...)
+	if sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	if sig[crypto.RecoveryIDOffset] > 1 {
+		return common.Address{}, errors.New("invalid signature recovery id")
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
@@ -0,0 +1,74 @@
+package signer
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// cow 是 EIP-712 规范示例中 Mail.from 的私钥 keccak256("cow")
+func cow(t *testing.T) *KeySigner {
+	key, err := crypto.ToECDSA(crypto.Keccak256([]byte("cow")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewKeySigner(key)
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	s := cow(t)
+	msg := []byte("hello world")
+	sig, err := SignMessage(s, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := sig[crypto.RecoveryIDOffset]; v != 27 && v != 28 {
+		t.Errorf("v = %d, want 27 or 28", v)
+	}
+	addr, err := RecoverMessage(msg, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if addr != s.Address() {
+		t.Errorf("recovered %s, want %s", addr.Hex(), s.Address().Hex())
+	}
+
+	// 改动消息后恢复出的是另一个地址，校验不通过
+	if ok, err := VerifyMessage(s.Address(), []byte("hello world!"), sig); err != nil || ok {
+		t.Errorf("tampered message verified: %v %v", ok, err)
+	}
+}
+
+// TestVerifyExternal checks personal_sign signatures made by other
+// implementations.
+func TestVerifyExternal(t *testing.T) {
+	tests := []struct {
+		name string
+		addr string
+		msg  []byte
+		sig  string
+	}{
+		{
+			// MetaMask eth-sig-util personalSign 的测试向量
+			name: "eth-sig-util",
+			addr: "0x29C76e6aD8f28BB1004902578Fb108c507Be341b",
+			msg:  []byte("Hello, world!"),
+			sig:  "0x90a938f7457df6e8f741264c32697fc52f9a8f867c52dd70713d9d2d472f2e415d9c94148991bbe1f4a1818d1dff09165782749c877f5cf1eff4ef126e55714d1c",
+		},
+		{
+			// JSON-RPC 文档中 eth_sign 的示例
+			name: "eth_sign",
+			addr: "0x9b2055d370f73ec7d8a03e965129118dc8f5bf83",
+			msg:  hexutil.MustDecode("0xdeadbeaf"),
+			sig:  "0xa3f20717a250c2b0b729b7e5becbff67fdaef7e0699da4de7ca5895b02a170a12d887fd3b17bfdce3481f10bea41f45ba9f709d39ce8325427b57afcfc994cee1b",
+		},
+	}
+	for _, tt := range tests {
+		ok, err := VerifyMessage(common.HexToAddress(tt.addr), tt.msg, hexutil.MustDecode(tt.sig))
+		if err != nil || !ok {
+			t.Errorf("%s: verify %v %v", tt.name, ok, err)
+		}
+	}
+}
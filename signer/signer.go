@@ -0,0 +1,83 @@
+// Package signer 提供统一的签名接口，私钥可以来自十六进制字符串或 keystore 文件。
+package signer
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"os"
+
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// ErrNoSigner is returned when neither a key nor a keystore is configured.
+var ErrNoSigner = errors.New("no signer configured, use --key or --keystore")
+
+// Signer signs 32-byte hashes on behalf of an address.
+type Signer interface {
+	Address() common.Address
+	// SignHash returns a 65-byte [R || S || V] signature with V in {0, 1}.
+	SignHash(hash []byte) ([]byte, error)
+}
+
+// KeySigner signs with an in-memory private key.
+type KeySigner struct {
+	key *ecdsa.PrivateKey
+}
+
+// NewKeySigner wraps a private key.
+func NewKeySigner(key *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{key: key}
+}
+
+// FromHex loads a hex encoded private key, with or without 0x prefix.
+func FromHex(hexkey string) (*KeySigner, error) {
+	if len(hexkey) > 1 && hexkey[:2] == "0x" {
+		hexkey = hexkey[2:]
+	}
+	key, err := crypto.HexToECDSA(hexkey)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeySigner(key), nil
+}
+
+// FromKeystore decrypts a keystore JSON file with password.
+func FromKeystore(file, password string) (*KeySigner, error) {
+	jsonBytes, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	key, err := keystore.DecryptKey(jsonBytes, password)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeySigner(key.PrivateKey), nil
+}
+
+// Load picks the signer from a hex key or a keystore file, key first.
+func Load(hexkey, keystoreFile, password string) (*KeySigner, error) {
+	switch {
+	case hexkey != "":
+		return FromHex(hexkey)
+	case keystoreFile != "":
+		return FromKeystore(keystoreFile, password)
+	}
+	return nil, ErrNoSigner
+}
+
+// Address returns the address of the key.
+func (s *KeySigner) Address() common.Address {
+	return crypto.PubkeyToAddress(s.key.PublicKey)
+}
+
+// SignHash signs hash with the key.
+func (s *KeySigner) SignHash(hash []byte) ([]byte, error) {
+	return crypto.Sign(hash, s.key)
+}
+
+// PrivateKey exposes the key for APIs such as bind.NewKeyedTransactorWithChainID.
+func (s *KeySigner) PrivateKey() *ecdsa.PrivateKey {
+	return s.key
+}
@@ -0,0 +1,42 @@
+package signer
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// TestTypedDataMail checks the Mail example of the EIP-712 specification.
+func TestTypedDataMail(t *testing.T) {
+	data, err := LoadTypedData("examples/mail.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := HashTypedData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := common.HexToHash("0xf2cee375fa42b42143804025fc449deafd50cc031ca257e0b194a650a912090f"); hash.DomainSeparator != want {
+		t.Errorf("domain separator %x, want %x", hash.DomainSeparator, want)
+	}
+	if want := common.HexToHash("0xbe609aee343fb3c4b28e1df9e632fca64fcfaede20f02e86244efddf30957bd2"); hash.Digest != want {
+		t.Errorf("digest %x, want %x", hash.Digest, want)
+	}
+
+	s := cow(t)
+	if want := common.HexToAddress("0xCD2a3d9F938E13CD947Ec05AbC7FE734Df8DD826"); s.Address() != want {
+		t.Fatalf("cow address %s, want %s", s.Address().Hex(), want.Hex())
+	}
+	sig, _, err := SignTypedData(s, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "0x4355c47d63924e8a72e509b65029052eb6c299d53a04e167c5775fd466751c9d07299936d304c153f6443dfa05f40ff007d72911b6f72307f996231605b915621c"
+	if got := hexutil.Encode(sig); got != want {
+		t.Errorf("signature %s, want %s", got, want)
+	}
+	if ok, err := VerifyTypedData(s.Address(), data, sig); err != nil || !ok {
+		t.Errorf("verify: %v %v", ok, err)
+	}
+}
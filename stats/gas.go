@@ -0,0 +1,344 @@
+// Package stats 统计一段区块的 gas 和手续费：每个区块的 gas 利用率、base fee、小费(priority fee)分位数、
+// 交易类型占比，以及按合约汇总的 gas 消耗排行，用来确定发送交易时的手续费策略。
+package stats
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Percentiles are the priority fee percentiles reported in the summary.
+var Percentiles = []float64{10, 25, 50, 75, 90}
+
+// Block is the gas usage of one block. Priority fees are the effective tips
+// paid per gas, in wei, over the transactions of the block.
+type Block struct {
+	Number      uint64    `json:"number"`
+	Time        time.Time `json:"time"`
+	GasUsed     uint64    `json:"gasUsed"`
+	GasLimit    uint64    `json:"gasLimit"`
+	Utilization float64   `json:"utilization"`
+	BaseFee     *big.Int  `json:"baseFee"`
+	Txs         int       `json:"txs"`
+	Legacy      int       `json:"legacy"`
+	AccessList  int       `json:"accessList"`
+	DynamicFee  int       `json:"dynamicFee"`
+	Blob        int       `json:"blob"`
+	PriorityP10 *big.Int  `json:"priorityFeeP10"`
+	PriorityP50 *big.Int  `json:"priorityFeeP50"`
+	PriorityP90 *big.Int  `json:"priorityFeeP90"`
+
+	tips []*big.Int
+}
+
+// Consumer is the gas used by the calls to one contract over the range.
+type Consumer struct {
+	Address common.Address `json:"address"`
+	Label   string         `json:"label,omitempty"`
+	Txs     int            `json:"txs"`
+	GasUsed uint64         `json:"gasUsed"`
+	Share   float64        `json:"share"`
+	Fees    *big.Int       `json:"fees"`
+}
+
+// Summary aggregates the whole range. Utilization is the share of the gas
+// limit used over all blocks, in percent.
+type Summary struct {
+	From        uint64     `json:"from"`
+	To          uint64     `json:"to"`
+	Blocks      int        `json:"blocks"`
+	Txs         int        `json:"txs"`
+	GasUsed     uint64     `json:"gasUsed"`
+	Utilization float64    `json:"utilization"`
+	BaseFeeMin  *big.Int   `json:"baseFeeMin"`
+	BaseFeeAvg  *big.Int   `json:"baseFeeAvg"`
+	BaseFeeMax  *big.Int   `json:"baseFeeMax"`
+	Priority    []*big.Int `json:"priorityFeePercentiles"`
+	Legacy      int        `json:"legacy"`
+	AccessList  int        `json:"accessList"`
+	DynamicFee  int        `json:"dynamicFee"`
+	Blob        int        `json:"blob"`
+}
+
+// Report is the result of Collect.
+type Report struct {
+	Summary   Summary    `json:"summary"`
+	Blocks    []Block    `json:"blocks"`
+	Consumers []Consumer `json:"consumers"`
+}
+
+// Options tunes Collect.
+type Options struct {
+	Workers int
+	// Top is the number of consumers kept, 0 for all.
+	Top int
+	// Progress is called after each block with the number of blocks done.
+	Progress func(done, total uint64)
+}
+
+// Collect fetches the blocks [from, to] with their receipts and aggregates
+// them.
+func Collect(ctx context.Context, client *ethclient.Client, c *rpc.Client, from, to uint64, opts Options) (*Report, error) {
+	if from > to {
+		return nil, fmt.Errorf("empty range %d-%d", from, to)
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = 8
+	}
+	total := to - from + 1
+	blocks := make([]Block, total)
+	consumers := make(map[common.Address]*Consumer)
+
+	numbers := make(chan uint64)
+	errc := make(chan error, workers)
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		done uint64
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for number := range numbers {
+				b, used, err := collectBlock(ctx, client, c, number)
+				if err != nil {
+					errc <- err
+					cancel()
+					return
+				}
+				mu.Lock()
+				blocks[number-from] = *b
+				for _, u := range used {
+					add(consumers, u)
+				}
+				done++
+				if opts.Progress != nil {
+					opts.Progress(done, total)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for number := from; number <= to; number++ {
+		select {
+		case numbers <- number:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(numbers)
+	wg.Wait()
+	select {
+	case err := <-errc:
+		return nil, err
+	default:
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	r := &Report{Blocks: blocks, Summary: summarize(from, to, blocks)}
+	for _, cons := range consumers {
+		if r.Summary.GasUsed > 0 {
+			cons.Share = percent(cons.GasUsed, r.Summary.GasUsed)
+		}
+		r.Consumers = append(r.Consumers, *cons)
+	}
+	sort.Slice(r.Consumers, func(i, j int) bool {
+		return r.Consumers[i].GasUsed > r.Consumers[j].GasUsed
+	})
+	if opts.Top > 0 && len(r.Consumers) > opts.Top {
+		r.Consumers = r.Consumers[:opts.Top]
+	}
+	return r, nil
+}
+
+// usage 是一笔调用合约的交易消耗的 gas 和支付的手续费
+type usage struct {
+	to   common.Address
+	gas  uint64
+	fees *big.Int
+}
+
+func add(consumers map[common.Address]*Consumer, u usage) {
+	cons, ok := consumers[u.to]
+	if !ok {
+		cons = &Consumer{Address: u.to, Fees: new(big.Int)}
+		consumers[u.to] = cons
+	}
+	cons.Txs++
+	cons.GasUsed += u.gas
+	cons.Fees.Add(cons.Fees, u.fees)
+}
+
+func collectBlock(ctx context.Context, client *ethclient.Client, c *rpc.Client, number uint64) (*Block, []usage, error) {
+	block, err := client.BlockByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return nil, nil, err
+	}
+	b := &Block{
+		Number:      number,
+		Time:        time.Unix(int64(block.Time()), 0).UTC(),
+		GasUsed:     block.GasUsed(),
+		GasLimit:    block.GasLimit(),
+		Utilization: percent(block.GasUsed(), block.GasLimit()),
+		BaseFee:     block.BaseFee(),
+		Txs:         len(block.Transactions()),
+	}
+	receipts, err := fetchReceipts(ctx, c, block.Transactions())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var used []usage
+	for i, tx := range block.Transactions() {
+		switch tx.Type() {
+		case types.LegacyTxType:
+			b.Legacy++
+		case types.AccessListTxType:
+			b.AccessList++
+		case types.DynamicFeeTxType:
+			b.DynamicFee++
+		case types.BlobTxType:
+			b.Blob++
+		}
+		// 伦敦升级前没有 base fee，小费就是 gas price
+		tip, err := tx.EffectiveGasTip(block.BaseFee())
+		if err != nil {
+			tip = new(big.Int)
+		}
+		b.tips = append(b.tips, tip)
+
+		// 只统计调用合约(带 calldata)和创建合约的交易
+		r := receipts[i]
+		switch {
+		case tx.To() == nil:
+			used = append(used, usage{r.ContractAddress, r.GasUsed, fee(tx, r)})
+		case len(tx.Data()) > 0:
+			used = append(used, usage{*tx.To(), r.GasUsed, fee(tx, r)})
+		}
+	}
+	sortBig(b.tips)
+	b.PriorityP10, b.PriorityP50, b.PriorityP90 = percentile(b.tips, 10), percentile(b.tips, 50), percentile(b.tips, 90)
+	return b, used, nil
+}
+
+// fetchReceipts 用 JSON-RPC 批量请求取回区块中所有交易的收据
+func fetchReceipts(ctx context.Context, c *rpc.Client, txs types.Transactions) ([]*types.Receipt, error) {
+	const chunk = 200
+	receipts := make([]*types.Receipt, len(txs))
+	for start := 0; start < len(txs); start += chunk {
+		end := start + chunk
+		if end > len(txs) {
+			end = len(txs)
+		}
+		elems := make([]rpc.BatchElem, end-start)
+		for i := range elems {
+			elems[i] = rpc.BatchElem{
+				Method: "eth_getTransactionReceipt",
+				Args:   []interface{}{txs[start+i].Hash()},
+				Result: &receipts[start+i],
+			}
+		}
+		if err := c.BatchCallContext(ctx, elems); err != nil {
+			return nil, err
+		}
+		for i, elem := range elems {
+			if elem.Error == nil && receipts[start+i] == nil {
+				elem.Error = fmt.Errorf("not found")
+			}
+			if elem.Error != nil {
+				return nil, fmt.Errorf("receipt of %s: %v", txs[start+i].Hash().Hex(), elem.Error)
+			}
+		}
+	}
+	return receipts, nil
+}
+
+func summarize(from, to uint64, blocks []Block) Summary {
+	s := Summary{From: from, To: to, Blocks: len(blocks)}
+	var (
+		limit   uint64
+		tips    []*big.Int
+		baseSum = new(big.Int)
+		based   int64
+	)
+	for _, b := range blocks {
+		s.Txs += b.Txs
+		s.GasUsed += b.GasUsed
+		limit += b.GasLimit
+		s.Legacy += b.Legacy
+		s.AccessList += b.AccessList
+		s.DynamicFee += b.DynamicFee
+		s.Blob += b.Blob
+		tips = append(tips, b.tips...)
+		if b.BaseFee == nil {
+			continue
+		}
+		if s.BaseFeeMin == nil || b.BaseFee.Cmp(s.BaseFeeMin) < 0 {
+			s.BaseFeeMin = b.BaseFee
+		}
+		if s.BaseFeeMax == nil || b.BaseFee.Cmp(s.BaseFeeMax) > 0 {
+			s.BaseFeeMax = b.BaseFee
+		}
+		baseSum.Add(baseSum, b.BaseFee)
+		based++
+	}
+	s.Utilization = percent(s.GasUsed, limit)
+	if based > 0 {
+		s.BaseFeeAvg = baseSum.Div(baseSum, big.NewInt(based))
+	}
+	sortBig(tips)
+	for _, p := range Percentiles {
+		s.Priority = append(s.Priority, percentile(tips, p))
+	}
+	return s
+}
+
+// percentile 用最近秩法取已排序 values 的第 p 百分位，没有数据时返回 nil
+func percentile(values []*big.Int, p float64) *big.Int {
+	if len(values) == 0 {
+		return nil
+	}
+	i := int(p/100*float64(len(values))+0.5) - 1
+	if i < 0 {
+		i = 0
+	}
+	if i >= len(values) {
+		i = len(values) - 1
+	}
+	return values[i]
+}
+
+func sortBig(values []*big.Int) {
+	sort.Slice(values, func(i, j int) bool { return values[i].Cmp(values[j]) < 0 })
+}
+
+func percent(a, b uint64) float64 {
+	if b == 0 {
+		return 0
+	}
+	return float64(a) / float64(b) * 100
+}
+
+func fee(tx *types.Transaction, r *types.Receipt) *big.Int {
+	price := r.EffectiveGasPrice
+	if price == nil {
+		price = tx.GasPrice()
+	}
+	return new(big.Int).Mul(price, new(big.Int).SetUint64(r.GasUsed))
+}
@@ -0,0 +1,44 @@
+package stats
+
+import (
+	"math"
+	"strings"
+)
+
+var sparks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline draws values as a line of block characters scaled between their
+// minimum and maximum. When there are more values than width, consecutive
+// values are averaged into width buckets.
+func Sparkline(values []float64, width int) string {
+	if width > 0 && len(values) > width {
+		values = bucket(values, width)
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	var sb strings.Builder
+	for _, v := range values {
+		i := 0
+		if hi > lo {
+			i = int((v - lo) / (hi - lo) * float64(len(sparks)-1))
+		}
+		sb.WriteRune(sparks[i])
+	}
+	return sb.String()
+}
+
+// bucket 把 values 平均分成 n 段，每段取平均值
+func bucket(values []float64, n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		start, end := i*len(values)/n, (i+1)*len(values)/n
+		var sum float64
+		for _, v := range values[start:end] {
+			sum += v
+		}
+		out[i] = sum / float64(end-start)
+	}
+	return out
+}
@@ -0,0 +1,284 @@
+// Package storage 根据 solc 输出的存储布局(--storage-layout)计算状态变量的存储位置，
+// 并通过 StorageAt 读取、解码为具体类型。合约没有 getter 或需要历史状态时都能直接读取。
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"yunlabs.com/goethereumbook/ethaddr"
+)
+
+// Layout is the storage layout emitted by solc --storage-layout.
+type Layout struct {
+	Storage []Variable      `json:"storage"`
+	Types   map[string]Type `json:"types"`
+}
+
+// Variable is a state variable or struct member in the layout.
+type Variable struct {
+	Label  string `json:"label"`
+	Offset int    `json:"offset"`
+	Slot   string `json:"slot"`
+	Type   string `json:"type"`
+}
+
+// Type describes how a type is laid out in storage.
+type Type struct {
+	Encoding      string     `json:"encoding"` // inplace, mapping, bytes, dynamic_array
+	Label         string     `json:"label"`
+	NumberOfBytes string     `json:"numberOfBytes"`
+	Key           string     `json:"key,omitempty"`
+	Value         string     `json:"value,omitempty"`
+	Base          string     `json:"base,omitempty"`
+	Members       []Variable `json:"members,omitempty"`
+}
+
+// Size returns the number of bytes the type occupies in storage.
+func (t Type) Size() int {
+	n, _ := strconv.Atoi(t.NumberOfBytes)
+	return n
+}
+
+// LoadLayout reads a solc storage layout JSON file.
+func LoadLayout(file string) (*Layout, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	layout := new(Layout)
+	if err := json.Unmarshal(data, layout); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	return layout, nil
+}
+
+// Location is where a value lives: the slot, the byte offset inside the
+// slot counted from the right, and its type.
+type Location struct {
+	Slot   common.Hash
+	Offset int
+	TypeID string
+	Type   Type
+}
+
+// Resolve computes the location of a path such as "decimals",
+// "balanceOf[0xE280...]", "allowance[0xA][0xB]", "items[foo]", "list[3]"
+// or "config.owner".
+func (l *Layout) Resolve(path string) (*Location, error) {
+	name, rest := splitPath(path)
+	for _, v := range l.Storage {
+		if v.Label == name {
+			loc, err := l.variable(v, common.Hash{})
+			if err != nil {
+				return nil, err
+			}
+			return l.walk(loc, rest)
+		}
+	}
+	return nil, fmt.Errorf("no state variable %q", name)
+}
+
+// variable 计算变量(或结构体成员)相对于 base 的位置
+func (l *Layout) variable(v Variable, base common.Hash) (*Location, error) {
+	slot, ok := new(big.Int).SetString(v.Slot, 10)
+	if !ok {
+		return nil, fmt.Errorf("%s: invalid slot %q", v.Label, v.Slot)
+	}
+	t, ok := l.Types[v.Type]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown type %s", v.Label, v.Type)
+	}
+	return &Location{Slot: addSlot(base, slot), Offset: v.Offset, TypeID: v.Type, Type: t}, nil
+}
+
+// walk 依次处理路径中剩下的 [key] 和 .member
+func (l *Layout) walk(loc *Location, path string) (*Location, error) {
+	for path != "" {
+		switch path[0] {
+		case '[':
+			end := strings.IndexByte(path, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("missing ] in %q", path)
+			}
+			key := path[1:end]
+			path = path[end+1:]
+
+			var err error
+			switch loc.Type.Encoding {
+			case "mapping":
+				loc, err = l.mappingValue(loc, key)
+			case "dynamic_array":
+				loc, err = l.arrayElement(loc, key, crypto.Keccak256Hash(loc.Slot.Bytes()))
+			case "inplace":
+				if loc.Type.Base == "" {
+					return nil, fmt.Errorf("%s is not indexable", loc.Type.Label)
+				}
+				loc, err = l.arrayElement(loc, key, loc.Slot) // 定长数组就地存放
+			default:
+				return nil, fmt.Errorf("%s is not indexable", loc.Type.Label)
+			}
+			if err != nil {
+				return nil, err
+			}
+		case '.':
+			path = path[1:]
+			member, rest := splitPath(path)
+			path = rest
+			next, err := l.member(loc, member)
+			if err != nil {
+				return nil, err
+			}
+			loc = next
+		default:
+			return nil, fmt.Errorf("unexpected %q in path", path)
+		}
+	}
+	return loc, nil
+}
+
+// mappingValue: mapping 中 key 的位置是 keccak256(h(key) . slot)
+func (l *Layout) mappingValue(loc *Location, key string) (*Location, error) {
+	keyType, ok := l.Types[loc.Type.Key]
+	if !ok {
+		return nil, fmt.Errorf("unknown key type %s", loc.Type.Key)
+	}
+	enc, err := EncodeKey(keyType, key)
+	if err != nil {
+		return nil, err
+	}
+	valueType, ok := l.Types[loc.Type.Value]
+	if !ok {
+		return nil, fmt.Errorf("unknown value type %s", loc.Type.Value)
+	}
+	slot := crypto.Keccak256Hash(enc, loc.Slot.Bytes())
+	return &Location{Slot: slot, TypeID: loc.Type.Value, Type: valueType}, nil
+}
+
+// arrayElement 计算数组第 i 个元素的位置。小于 16 字节的元素会紧凑打包在同一个 slot 中
+func (l *Layout) arrayElement(loc *Location, key string, start common.Hash) (*Location, error) {
+	idx, ok := new(big.Int).SetString(key, 0)
+	if !ok || idx.Sign() < 0 {
+		return nil, fmt.Errorf("invalid array index %q", key)
+	}
+	base, ok := l.Types[loc.Type.Base]
+	if !ok {
+		return nil, fmt.Errorf("unknown element type %s", loc.Type.Base)
+	}
+	size := base.Size()
+	if size == 0 {
+		return nil, fmt.Errorf("invalid element size for %s", base.Label)
+	}
+	if size >= 32 {
+		slots := big.NewInt(int64((size + 31) / 32))
+		return &Location{Slot: addSlot(start, new(big.Int).Mul(idx, slots)), TypeID: loc.Type.Base, Type: base}, nil
+	}
+	perSlot := big.NewInt(int64(32 / size))
+	slot, rem := new(big.Int).QuoRem(idx, perSlot, new(big.Int))
+	return &Location{Slot: addSlot(start, slot), Offset: int(rem.Int64()) * size, TypeID: loc.Type.Base, Type: base}, nil
+}
+
+func (l *Layout) member(loc *Location, name string) (*Location, error) {
+	for _, m := range loc.Type.Members {
+		if m.Label == name {
+			return l.variable(m, loc.Slot)
+		}
+	}
+	return nil, fmt.Errorf("%s has no member %q", loc.Type.Label, name)
+}
+
+// MappingSlot returns the slot of key in a mapping stored at slot, for value
+// type keys already padded to 32 bytes or shorter ones that get left padded.
+func MappingSlot(key []byte, slot uint64) common.Hash {
+	return crypto.Keccak256Hash(common.LeftPadBytes(key, 32), common.LeftPadBytes(new(big.Int).SetUint64(slot).Bytes(), 32))
+}
+
+// EncodeKey encodes a mapping key given as text according to its type:
+// addresses and integers are left padded, bytesN are right padded (plain
+// text such as "foo" is taken as its bytes), string and bytes keys are used
+// unpadded.
+func EncodeKey(t Type, key string) ([]byte, error) {
+	label := t.Label
+	switch {
+	case label == "address" || strings.HasPrefix(label, "contract "):
+		addr, err := ethaddr.Parse(key)
+		if err != nil {
+			return nil, err
+		}
+		return common.LeftPadBytes(addr.Bytes(), 32), nil
+	case label == "bool":
+		b, err := strconv.ParseBool(key)
+		if err != nil {
+			return nil, err
+		}
+		if b {
+			return common.LeftPadBytes([]byte{1}, 32), nil
+		}
+		return make([]byte, 32), nil
+	case strings.HasPrefix(label, "uint") || strings.HasPrefix(label, "enum "):
+		n, ok := new(big.Int).SetString(key, 0)
+		if !ok || n.Sign() < 0 {
+			return nil, fmt.Errorf("invalid %s key %q", label, key)
+		}
+		return common.LeftPadBytes(n.Bytes(), 32), nil
+	case strings.HasPrefix(label, "int"):
+		n, ok := new(big.Int).SetString(key, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s key %q", label, key)
+		}
+		return common.BigToHash(toTwos(n)).Bytes(), nil
+	case label == "string":
+		return []byte(key), nil
+	case label == "bytes":
+		return decodeBytesKey(key)
+	case strings.HasPrefix(label, "bytes"):
+		b, err := decodeBytesKey(key)
+		if err != nil {
+			return nil, err
+		}
+		if len(b) > 32 {
+			return nil, fmt.Errorf("%s key too long", label)
+		}
+		return common.RightPadBytes(b, 32), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", label)
+}
+
+func decodeBytesKey(key string) ([]byte, error) {
+	if strings.HasPrefix(key, "0x") {
+		return hexutil.Decode(key)
+	}
+	return []byte(key), nil
+}
+
+// toTwos 把负数转换成 256 位补码
+func toTwos(n *big.Int) *big.Int {
+	if n.Sign() >= 0 {
+		return n
+	}
+	return new(big.Int).Add(n, new(big.Int).Lsh(big.NewInt(1), 256))
+}
+
+func addSlot(base common.Hash, n *big.Int) common.Hash {
+	sum := new(big.Int).Add(base.Big(), n)
+	return common.BigToHash(sum.Mod(sum, new(big.Int).Lsh(big.NewInt(1), 256)))
+}
+
+// splitPath 拆出开头的标识符和剩余部分
+func splitPath(path string) (string, string) {
+	if i := strings.IndexAny(path, "[."); i >= 0 {
+		return path[:i], path[i:]
+	}
+	return path, ""
+}
+
+var errNeedKey = errors.New("mappings can only be read with a key, e.g. balanceOf[0x...]")
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Reader reads raw storage words, ethclient.Client satisfies it.
+type Reader interface {
+	StorageAt(ctx context.Context, account common.Address, key common.Hash, blockNumber *big.Int) ([]byte, error)
+}
+
+// Member is a decoded struct member.
+type Member struct {
+	Name  string
+	Value interface{}
+}
+
+// Read loads and decodes the value at loc from contract's storage at block
+// (nil for latest). The result is a *big.Int, common.Address, bool, string,
+// []byte, []Member for structs, or the array length for dynamic arrays.
+func Read(ctx context.Context, r Reader, l *Layout, contract common.Address, loc *Location, block *big.Int) (interface{}, error) {
+	word := func(slot common.Hash) (common.Hash, error) {
+		enc, err := r.StorageAt(ctx, contract, slot, block)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		return common.BytesToHash(enc), nil
+	}
+
+	switch loc.Type.Encoding {
+	case "mapping":
+		return nil, errNeedKey
+	case "dynamic_array":
+		length, err := word(loc.Slot)
+		if err != nil {
+			return nil, err
+		}
+		return length.Big(), nil
+	case "bytes":
+		return readBytes(word, loc)
+	}
+
+	// 结构体按成员逐个读取
+	if len(loc.Type.Members) > 0 {
+		var members []Member
+		for _, m := range loc.Type.Members {
+			mloc, err := l.variable(m, loc.Slot)
+			if err != nil {
+				return nil, err
+			}
+			v, err := Read(ctx, r, l, contract, mloc, block)
+			if err != nil {
+				return nil, err
+			}
+			members = append(members, Member{Name: m.Label, Value: v})
+		}
+		return members, nil
+	}
+	if loc.Type.Base != "" {
+		return nil, fmt.Errorf("%s: read elements with an index", loc.Type.Label)
+	}
+
+	w, err := word(loc.Slot)
+	if err != nil {
+		return nil, err
+	}
+	return DecodeInplace(loc.Type, w, loc.Offset)
+}
+
+// maxBytesLength 是读取 string/bytes 的长度上限
+const maxBytesLength = 1 << 20
+
+// readBytes 解码 string/bytes：短于 32 字节时数据和 length*2 放在同一个 slot，
+// 否则 slot 中存 length*2+1，数据从 keccak256(slot) 开始连续存放
+func readBytes(word func(common.Hash) (common.Hash, error), loc *Location) (interface{}, error) {
+	head, err := word(loc.Slot)
+	if err != nil {
+		return nil, err
+	}
+	var data []byte
+	if head[31]&1 == 0 {
+		length := int(head[31]) / 2
+		if length > 31 {
+			return nil, fmt.Errorf("%s at slot %s: short length %d exceeds 31 bytes, wrong layout or corrupted slot", loc.Type.Label, loc.Slot.Hex(), length)
+		}
+		data = head[:length]
+	} else {
+		// 长度直接来自存储，slot 损坏或布局不对时可能大得离谱，先检查再逐个 slot 读取
+		n := new(big.Int).Rsh(head.Big(), 1)
+		if !n.IsUint64() || n.Uint64() > maxBytesLength {
+			return nil, fmt.Errorf("%s at slot %s: length %v exceeds %d bytes, wrong layout or corrupted slot", loc.Type.Label, loc.Slot.Hex(), n, maxBytesLength)
+		}
+		length := n.Uint64()
+		start := crypto.Keccak256Hash(loc.Slot.Bytes())
+		for i := uint64(0); uint64(len(data)) < length; i++ {
+			w, err := word(addSlot(start, new(big.Int).SetUint64(i)))
+			if err != nil {
+				return nil, err
+			}
+			data = append(data, w.Bytes()...)
+		}
+		data = data[:length]
+	}
+	if loc.Type.Label == "string" {
+		return string(data), nil
+	}
+	return data, nil
+}
+
+// DecodeInplace extracts a value type stored at offset (counted from the
+// right) inside word.
+func DecodeInplace(t Type, word common.Hash, offset int) (interface{}, error) {
+	size := t.Size()
+	if size <= 0 || offset+size > 32 {
+		return nil, fmt.Errorf("%s: invalid size %d at offset %d", t.Label, size, offset)
+	}
+	raw := word[32-offset-size : 32-offset]
+
+	label := t.Label
+	switch {
+	case label == "address" || strings.HasPrefix(label, "contract "):
+		return common.BytesToAddress(raw), nil
+	case label == "bool":
+		return raw[len(raw)-1] != 0, nil
+	case strings.HasPrefix(label, "uint") || strings.HasPrefix(label, "enum "):
+		return new(big.Int).SetBytes(raw), nil
+	case strings.HasPrefix(label, "int"):
+		n := new(big.Int).SetBytes(raw)
+		if raw[0]&0x80 != 0 {
+			n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(8*size)))
+		}
+		return n, nil
+	case strings.HasPrefix(label, "bytes"):
+		// bytesN 左对齐，这里取到的正好是原始字节
+		return append([]byte{}, raw...), nil
+	}
+	return append([]byte{}, raw...), nil
+}
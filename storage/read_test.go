@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestReadBytes(t *testing.T) {
+	slot := common.BigToHash(big.NewInt(3))
+	data := crypto.Keccak256Hash(slot.Bytes())
+	long := strings.Repeat("0123456789", 5) // 50 字节，占两个 slot
+
+	// short 是短字符串的 slot：数据靠左，最后一个字节是 length*2
+	short := func(s string) common.Hash {
+		var h common.Hash
+		copy(h[:], s)
+		h[31] = byte(2 * len(s))
+		return h
+	}
+	tests := []struct {
+		name    string
+		head    common.Hash
+		label   string
+		want    interface{}
+		wantErr bool
+	}{
+		{name: "empty", head: common.Hash{}, label: "string", want: ""},
+		{name: "short", head: short("MyToken"), label: "string", want: "MyToken"},
+		{name: "short 31 bytes", head: short(long[:31]), label: "string", want: long[:31]},
+		{name: "short bytes", head: short("\x01\x02"), label: "bytes", want: []byte{1, 2}},
+		{name: "long", head: common.BigToHash(big.NewInt(2*50 + 1)), label: "string", want: long},
+		// 把存着 100 的 uint slot 当作 string 读：偶数，但长度 50 放不进一个 slot
+		{name: "even head too long", head: common.BigToHash(big.NewInt(100)), label: "string", wantErr: true},
+		{name: "even head 64", head: common.BigToHash(big.NewInt(64)), label: "string", wantErr: true},
+		{name: "odd head oversized", head: common.BigToHash(big.NewInt(2<<20 + 3)), label: "bytes", wantErr: true},
+		{name: "odd head huge", head: common.HexToHash("0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"), label: "string", wantErr: true},
+	}
+	for _, tt := range tests {
+		storage := map[common.Hash]common.Hash{
+			slot:                         tt.head,
+			data:                         common.BytesToHash([]byte(long[:32])),
+			addSlot(data, big.NewInt(1)): common.BytesToHash(append([]byte(long[32:]), make([]byte, 14)...)),
+		}
+		word := func(h common.Hash) (common.Hash, error) {
+			w, ok := storage[h]
+			if !ok {
+				return common.Hash{}, errors.New("unexpected slot " + h.Hex())
+			}
+			return w, nil
+		}
+		got, err := readBytes(word, &Location{Slot: slot, Type: Type{Encoding: "bytes", Label: tt.label}})
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: got %v, want an error", tt.name, got)
+			} else if !strings.Contains(err.Error(), "wrong layout or corrupted slot") {
+				t.Errorf("%s: error %v", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if b, ok := tt.want.([]byte); ok {
+			if string(got.([]byte)) != string(b) {
+				t.Errorf("%s: got %x, want %x", tt.name, got, b)
+			}
+		} else if got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
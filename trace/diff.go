@@ -0,0 +1,100 @@
+package trace
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Change is one field of one account modified by the transaction. Field is
+// balance, nonce, code or storage; Slot is set for storage.
+type Change struct {
+	Address common.Address `json:"address"`
+	Field   string         `json:"field"`
+	Slot    *common.Hash   `json:"slot,omitempty"`
+	Before  string         `json:"before"`
+	After   string         `json:"after"`
+}
+
+// Changes lists the modifications in d, sorted by address. The post state
+// of diff mode only carries changed fields: a storage slot missing from it
+// was cleared, and an account missing from it was destroyed.
+func (d *StateDiff) Changes() []Change {
+	if d == nil {
+		return nil
+	}
+	var addrs []common.Address
+	seen := make(map[common.Address]bool)
+	for _, m := range []map[common.Address]*Account{d.Pre, d.Post} {
+		for addr := range m {
+			if !seen[addr] {
+				seen[addr] = true
+				addrs = append(addrs, addr)
+			}
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	var changes []Change
+	for _, addr := range addrs {
+		pre, post := d.Pre[addr], d.Post[addr]
+		destroyed := post == nil
+		if pre == nil {
+			pre = new(Account)
+		}
+		if post == nil {
+			post = new(Account)
+		}
+		add := func(field string, slot *common.Hash, before, after string) {
+			if before != after {
+				changes = append(changes, Change{Address: addr, Field: field, Slot: slot, Before: before, After: after})
+			}
+		}
+
+		if post.Balance != nil || destroyed {
+			add("balance", nil, bigString(pre.Balance), bigString(post.Balance))
+		}
+		if post.Nonce != 0 || destroyed {
+			add("nonce", nil, strconv.FormatUint(pre.Nonce, 10), strconv.FormatUint(post.Nonce, 10))
+		}
+		if len(post.Code) > 0 || destroyed {
+			add("code", nil, codeString(pre.Code), codeString(post.Code))
+		}
+
+		var slots []common.Hash
+		for slot := range pre.Storage {
+			slots = append(slots, slot)
+		}
+		for slot := range post.Storage {
+			if _, ok := pre.Storage[slot]; !ok {
+				slots = append(slots, slot)
+			}
+		}
+		sort.Slice(slots, func(i, j int) bool { return bytes.Compare(slots[i][:], slots[j][:]) < 0 })
+		for _, slot := range slots {
+			slot := slot
+			add("storage", &slot, pre.Storage[slot].Hex(), post.Storage[slot].Hex())
+		}
+	}
+	return changes
+}
+
+func bigString(v *hexutil.Big) string {
+	if v == nil {
+		return "0"
+	}
+	return v.ToInt().String()
+}
+
+// codeString 代码太长，只显示长度和哈希
+func codeString(code []byte) string {
+	if len(code) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%d bytes %s", len(code), crypto.Keccak256Hash(code).Hex()[:10])
+}
@@ -0,0 +1,195 @@
+// Package trace 通过 debug_traceTransaction 取得交易执行的调用树(callTracer)和状态变化(prestateTracer 的 diffMode)，
+// 用已知 ABI 解码每一层调用的方法名、参数和 revert 原因。节点没有开放 debug API 时返回 ErrNoDebug，
+// 调用方可以退回只看收据；保存下来的 trace 文件可以离线重放。
+package trace
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrNoDebug is returned when the node does not expose debug_traceTransaction
+// or the requested tracer.
+var ErrNoDebug = errors.New("node does not support debug_traceTransaction")
+
+// CallFrame is one call in the callTracer output. Method is not part of the
+// tracer output, it is filled in by Annotate.
+type CallFrame struct {
+	Type         string          `json:"type"`
+	From         common.Address  `json:"from"`
+	To           *common.Address `json:"to,omitempty"`
+	Value        *hexutil.Big    `json:"value,omitempty"`
+	Gas          hexutil.Uint64  `json:"gas"`
+	GasUsed      hexutil.Uint64  `json:"gasUsed"`
+	Input        hexutil.Bytes   `json:"input"`
+	Output       hexutil.Bytes   `json:"output,omitempty"`
+	Error        string          `json:"error,omitempty"`
+	RevertReason string          `json:"revertReason,omitempty"`
+	Method       string          `json:"method,omitempty"`
+	Calls        []*CallFrame    `json:"calls,omitempty"`
+}
+
+// Reverted reports whether the call failed.
+func (f *CallFrame) Reverted() bool {
+	return f.Error != ""
+}
+
+// Wei returns the value transferred by the call, zero when there is none.
+func (f *CallFrame) Wei() *big.Int {
+	if f.Value == nil {
+		return new(big.Int)
+	}
+	return f.Value.ToInt()
+}
+
+// Account is an account in the prestateTracer output. In diff mode the
+// post state only carries the fields that changed.
+type Account struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   uint64                      `json:"nonce,omitempty"`
+	Code    hexutil.Bytes               `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+}
+
+// StateDiff is the prestateTracer output with diffMode enabled.
+type StateDiff struct {
+	Pre  map[common.Address]*Account `json:"pre"`
+	Post map[common.Address]*Account `json:"post"`
+}
+
+// Trace is everything known about the execution of one transaction. Calls
+// or StateDiff is nil when the node could not produce it.
+type Trace struct {
+	Hash      common.Hash `json:"hash"`
+	Calls     *CallFrame  `json:"calls,omitempty"`
+	StateDiff *StateDiff  `json:"stateDiff,omitempty"`
+}
+
+// Fetch traces the transaction with the callTracer and the prestateTracer.
+// A node without the prestateTracer still yields the call tree; ErrNoDebug
+// is only returned when not even the call tree is available.
+func Fetch(ctx context.Context, c *rpc.Client, hash common.Hash) (*Trace, error) {
+	t := &Trace{Hash: hash}
+	if err := c.CallContext(ctx, &t.Calls, "debug_traceTransaction", hash, map[string]interface{}{
+		"tracer": "callTracer",
+	}); err != nil {
+		return nil, debugErr(err)
+	}
+	if err := c.CallContext(ctx, &t.StateDiff, "debug_traceTransaction", hash, map[string]interface{}{
+		"tracer":       "prestateTracer",
+		"tracerConfig": map[string]interface{}{"diffMode": true},
+	}); err != nil {
+		if err := debugErr(err); !errors.Is(err, ErrNoDebug) {
+			return nil, err
+		}
+		t.StateDiff = nil
+	}
+	return t, nil
+}
+
+// FetchBlock returns the call trees of every transaction in the block by
+// transaction hash. Results are matched to the transactions by position,
+// since not every client sets txHash in the debug_traceBlockByHash output.
+func FetchBlock(ctx context.Context, c *rpc.Client, block *types.Block) (map[common.Hash]*CallFrame, error) {
+	var results []struct {
+		Result *CallFrame `json:"result"`
+		Error  string     `json:"error"`
+	}
+	if err := c.CallContext(ctx, &results, "debug_traceBlockByHash", block.Hash(), map[string]interface{}{
+		"tracer": "callTracer",
+	}); err != nil {
+		return nil, debugErr(err)
+	}
+	txs := block.Transactions()
+	if len(results) != len(txs) {
+		return nil, fmt.Errorf("block %x: %d traces for %d transactions", block.Hash(), len(results), len(txs))
+	}
+	calls := make(map[common.Hash]*CallFrame, len(results))
+	for i, r := range results {
+		if r.Error != "" {
+			return nil, fmt.Errorf("trace %x: %s", txs[i].Hash(), r.Error)
+		}
+		calls[txs[i].Hash()] = r.Result
+	}
+	return calls, nil
+}
+
+// debugErr 把“方法不存在”“tracer 不存在”之类的错误统一成 ErrNoDebug
+func debugErr(err error) error {
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == -32601 {
+		return fmt.Errorf("%w: %v", ErrNoDebug, err)
+	}
+	msg := err.Error()
+	for _, s := range []string{"does not exist", "not available", "not supported", "tracer not found"} {
+		if strings.Contains(msg, s) {
+			return fmt.Errorf("%w: %v", ErrNoDebug, err)
+		}
+	}
+	return err
+}
+
+// FromReceipt builds the top level frame from the transaction and its
+// receipt, for nodes without the debug API. Internal calls are unknown.
+func FromReceipt(tx *types.Transaction, from common.Address, receipt *types.Receipt) *CallFrame {
+	f := &CallFrame{
+		Type:    "CALL",
+		From:    from,
+		To:      tx.To(),
+		Value:   (*hexutil.Big)(tx.Value()),
+		Gas:     hexutil.Uint64(tx.Gas()),
+		GasUsed: hexutil.Uint64(receipt.GasUsed),
+		Input:   tx.Data(),
+	}
+	if tx.To() == nil {
+		f.Type = "CREATE"
+		addr := receipt.ContractAddress
+		f.To = &addr
+	}
+	if receipt.Status == types.ReceiptStatusFailed {
+		f.Error = "execution reverted"
+	}
+	return f
+}
+
+// Load reads a trace saved with Save, or the bare callTracer output of
+// debug_traceTransaction.
+func Load(file string) (*Trace, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	t := new(Trace)
+	if err := json.Unmarshal(data, t); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if t.Calls == nil && t.StateDiff == nil {
+		// 没有 calls/stateDiff 字段时当作直接保存的 callTracer 结果
+		if err := json.Unmarshal(data, &t.Calls); err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		if t.Calls.Type == "" {
+			return nil, fmt.Errorf("%s: no call trace", file)
+		}
+	}
+	return t, nil
+}
+
+// Save writes t as indented JSON for Load.
+func Save(file string, t *Trace) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0644)
+}
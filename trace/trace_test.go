@@ -0,0 +1,82 @@
+package trace
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+
+	"yunlabs.com/goethereumbook/registry"
+)
+
+// examples/aggregate3.json 是一笔 Multicall3.aggregate3 交易的 callTracer 和 prestateTracer 输出：
+// 第一个子调用 transfer 因余额不足失败(allowFailure)，第二个 balanceOf 成功
+func loadExample(t *testing.T) *Trace {
+	tr, err := Load("examples/aggregate3.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 去掉节点给出的 revertReason，由 Annotate 从 output 解码
+	Walk(tr.Calls, func(f *CallFrame, depth int) { f.RevertReason = "" })
+	Annotate(tr.Calls, registry.Default())
+	return tr
+}
+
+func TestAnnotateAggregate3(t *testing.T) {
+	tr := loadExample(t)
+	rows := Flatten(tr.Calls)
+	if len(rows) != 3 {
+		t.Fatalf("got %d frames, want 3", len(rows))
+	}
+	if m := rows[0].Method; !strings.HasPrefix(m, "Multicall3.aggregate3(") {
+		t.Errorf("top level method %q, want Multicall3.aggregate3", m)
+	}
+	if rows[0].Error != "" {
+		t.Errorf("top level call failed: %s", rows[0].Error)
+	}
+
+	transfer := rows[1]
+	if transfer.Depth != 1 || !strings.HasPrefix(transfer.Method, "ERC20.transfer(") {
+		t.Errorf("first subcall %d %q, want ERC20.transfer at depth 1", transfer.Depth, transfer.Method)
+	}
+	if want := "execution reverted: Insufficient balance"; transfer.Error != want {
+		t.Errorf("first subcall error %q, want %q", transfer.Error, want)
+	}
+	if rows[2].Error != "" || !strings.HasPrefix(rows[2].Method, "ERC20.balanceOf(") {
+		t.Errorf("second subcall %q %q, want a successful ERC20.balanceOf", rows[2].Method, rows[2].Error)
+	}
+
+	var buf bytes.Buffer
+	WriteTree(&buf, tr.Calls, func(a common.Address) string { return a.Hex() })
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("tree has %d lines, want 3:\n%s", len(lines), buf.String())
+	}
+	if !strings.HasPrefix(lines[1], "├─ CALL") || !strings.HasSuffix(lines[1], "REVERTED(execution reverted: Insufficient balance)") {
+		t.Errorf("tree line %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[2], "└─ CALL") {
+		t.Errorf("tree line %q", lines[2])
+	}
+}
+
+func TestChangesAggregate3(t *testing.T) {
+	tr := loadExample(t)
+	sender := common.HexToAddress("0xe280029a7867ba5c9154434886c241775ea87e53")
+	got := make(map[string]Change)
+	for _, c := range tr.StateDiff.Changes() {
+		if c.Address == sender {
+			got[c.Field] = c
+		}
+	}
+	if len(got) != 2 {
+		t.Fatalf("sender changes %v, want balance and nonce", got)
+	}
+	if c := got["balance"]; c.Before != "998989999999958436181" || c.After != "998989999999958133140" {
+		t.Errorf("balance %s → %s", c.Before, c.After)
+	}
+	if c := got["nonce"]; c.Before != "15" || c.After != "16" {
+		t.Errorf("nonce %s → %s", c.Before, c.After)
+	}
+}
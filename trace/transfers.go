@@ -0,0 +1,67 @@
+package trace
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Transfer is an ETH movement made by a contract inside a transaction: a
+// CALL with value, a CREATE funding the new contract, or a SELFDESTRUCT
+// sending the remaining balance away. The value of the transaction itself is
+// not included.
+type Transfer struct {
+	TxHash common.Hash    `json:"transactionHash"`
+	Type   string         `json:"type"`
+	Depth  int            `json:"depth"`
+	From   common.Address `json:"from"`
+	To     common.Address `json:"to"`
+	Value  *big.Int       `json:"value"`
+}
+
+// Transfers extracts the internal ETH transfers of the transaction hash from
+// its call tree. Calls that reverted, and everything below them, moved
+// nothing and are skipped.
+func Transfers(hash common.Hash, root *CallFrame) []Transfer {
+	var transfers []Transfer
+	var walk func(f *CallFrame, depth int)
+	walk = func(f *CallFrame, depth int) {
+		if f.Reverted() {
+			return
+		}
+		// DELEGATECALL/CALLCODE 带的 value 是上层调用的，STATICCALL 不能转账
+		switch f.Type {
+		case "CALL", "CREATE", "CREATE2", "SELFDESTRUCT":
+			if depth > 0 && f.To != nil && f.Wei().Sign() > 0 {
+				transfers = append(transfers, Transfer{TxHash: hash, Type: f.Type, Depth: depth, From: f.From, To: *f.To, Value: f.Wei()})
+			}
+		}
+		for _, c := range f.Calls {
+			walk(c, depth+1)
+		}
+	}
+	if root != nil {
+		walk(root, 0)
+	}
+	return transfers
+}
+
+// BlockTransfers traces every transaction of the block with one
+// debug_traceBlockByHash call and returns the internal transfers by
+// transaction hash.
+func BlockTransfers(ctx context.Context, c *rpc.Client, block *types.Block) (map[common.Hash][]Transfer, error) {
+	calls, err := FetchBlock(ctx, c, block)
+	if err != nil {
+		return nil, err
+	}
+	transfers := make(map[common.Hash][]Transfer)
+	for hash, root := range calls {
+		if list := Transfers(hash, root); len(list) > 0 {
+			transfers[hash] = list
+		}
+	}
+	return transfers, nil
+}
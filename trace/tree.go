@@ -0,0 +1,141 @@
+package trace
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+
+	"yunlabs.com/goethereumbook/registry"
+	"yunlabs.com/goethereumbook/units"
+)
+
+// Walk calls fn for every frame, parents before children, with the depth of
+// the frame (0 for the top level call).
+func Walk(root *CallFrame, fn func(f *CallFrame, depth int)) {
+	var walk func(f *CallFrame, depth int)
+	walk = func(f *CallFrame, depth int) {
+		fn(f, depth)
+		for _, c := range f.Calls {
+			walk(c, depth+1)
+		}
+	}
+	if root != nil {
+		walk(root, 0)
+	}
+}
+
+// Annotate decodes the method of every frame against reg, and the revert
+// reason of failed frames when the node did not provide one.
+func Annotate(root *CallFrame, reg *registry.Registry) {
+	Walk(root, func(f *CallFrame, depth int) {
+		if call, err := reg.DecodeCall(f.Input); err == nil {
+			f.Method = call.String()
+			if !f.Reverted() {
+				if ret := call.Returns(f.Output); ret != "" {
+					f.Method += " → " + ret
+				}
+			}
+		}
+		if f.Reverted() && f.RevertReason == "" {
+			if reason, ok := reg.DecodeRevert(f.Output); ok {
+				f.RevertReason = reason
+			}
+		}
+	})
+}
+
+// Row is a frame flattened for tabular output.
+type Row struct {
+	Depth   int             `json:"depth"`
+	Type    string          `json:"type"`
+	From    common.Address  `json:"from"`
+	To      *common.Address `json:"to"`
+	Value   *big.Int        `json:"value"`
+	Gas     uint64          `json:"gas"`
+	GasUsed uint64          `json:"gasUsed"`
+	Method  string          `json:"method"`
+	Error   string          `json:"error"`
+}
+
+// Flatten lists the frames depth first.
+func Flatten(root *CallFrame) []Row {
+	var rows []Row
+	Walk(root, func(f *CallFrame, depth int) {
+		rows = append(rows, Row{
+			Depth:   depth,
+			Type:    f.Type,
+			From:    f.From,
+			To:      f.To,
+			Value:   f.Wei(),
+			Gas:     uint64(f.Gas),
+			GasUsed: uint64(f.GasUsed),
+			Method:  f.method(),
+			Error:   f.failure(),
+		})
+	})
+	return rows
+}
+
+// method 返回解码后的方法，无法解码时给出选择器；没有 calldata 的 CALL 是单纯的转账
+func (f *CallFrame) method() string {
+	switch {
+	case f.Method != "":
+		return f.Method
+	case strings.HasPrefix(f.Type, "CREATE"):
+		return ""
+	case len(f.Input) == 0:
+		return "transfer"
+	case len(f.Input) < 4:
+		return hexutil.Encode(f.Input)
+	}
+	return hexutil.Encode(f.Input[:4]) + "(…)"
+}
+
+func (f *CallFrame) failure() string {
+	if !f.Reverted() {
+		return ""
+	}
+	if f.RevertReason != "" {
+		return f.Error + ": " + f.RevertReason
+	}
+	return f.Error
+}
+
+// WriteTree prints the call tree, one frame per line. name renders an
+// address, e.g. with its address book label.
+func WriteTree(w io.Writer, root *CallFrame, name func(common.Address) string) {
+	var write func(f *CallFrame, prefix, branch, indent string)
+	write = func(f *CallFrame, prefix, branch, indent string) {
+		to := "(new contract)"
+		if f.To != nil {
+			to = name(*f.To)
+		}
+		line := fmt.Sprintf("%s%s%s %s → %s", prefix, branch, f.Type, name(f.From), to)
+		if m := f.method(); m != "" {
+			line += " " + m
+		}
+		if v := f.Wei(); v.Sign() > 0 {
+			line += " value=" + units.FormatEther(v)
+		}
+		line += fmt.Sprintf(" gas=%d/%d", uint64(f.GasUsed), uint64(f.Gas))
+		if f.Reverted() {
+			line += " REVERTED(" + f.failure() + ")"
+		}
+		fmt.Fprintln(w, line)
+
+		for i, c := range f.Calls {
+			if i == len(f.Calls)-1 {
+				write(c, prefix+indent, "└─ ", "   ")
+			} else {
+				write(c, prefix+indent, "├─ ", "│  ")
+			}
+		}
+	}
+	if root != nil {
+		write(root, "", "", "")
+	}
+}
@@ -0,0 +1,225 @@
+// Package units 在十进制金额和最小单位整数之间精确转换，不经过浮点数。
+// "1.5 ether"、"30 gwei"、"12.34 MTK" 按单位的小数位数解析成 *big.Int，
+// 超出小数位数、会丢失精度的金额直接报错；格式化时可以控制小数位数、舍入方式和千位分组。
+package units
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Unit is a named denomination with the number of decimals of its smallest
+// unit, e.g. ether has 18 and an ERC20 token has its decimals().
+type Unit struct {
+	Name     string
+	Decimals uint8
+}
+
+var (
+	Wei    = Unit{"wei", 0}
+	Kwei   = Unit{"kwei", 3}
+	Mwei   = Unit{"mwei", 6}
+	Gwei   = Unit{"gwei", 9}
+	Szabo  = Unit{"szabo", 12}
+	Finney = Unit{"finney", 15}
+	Ether  = Unit{"ether", 18}
+
+	// ETH is ether as shown in printouts.
+	ETH = Unit{"ETH", 18}
+)
+
+// EtherUnits are the denominations accepted for ETH amounts.
+var EtherUnits = []Unit{Wei, Kwei, Mwei, Gwei, Szabo, Finney, Ether, ETH}
+
+var (
+	ErrSyntax    = errors.New("invalid amount")
+	ErrPrecision = errors.New("amount has more decimals than the unit allows")
+	ErrUnit      = errors.New("unknown unit")
+)
+
+// Parse converts a non-negative decimal string such as "1.5" to the
+// smallest unit of a denomination with the given decimals. Underscores may
+// be used as digit separators. Digits beyond decimals are rejected unless
+// they are zeros, and so are signs: amounts go into values and filters where
+// a negative number makes no sense. Use ParseSigned to accept them.
+func Parse(s string, decimals uint8) (*big.Int, error) {
+	num := strings.ReplaceAll(strings.TrimSpace(s), "_", "")
+	if strings.HasPrefix(num, "-") || strings.HasPrefix(num, "+") {
+		return nil, fmt.Errorf("%w %q: sign not allowed", ErrSyntax, s)
+	}
+	return parse(s, num, decimals)
+}
+
+// ParseSigned is Parse accepting one leading "-" or "+", e.g. "-0.25".
+func ParseSigned(s string, decimals uint8) (*big.Int, error) {
+	num := strings.ReplaceAll(strings.TrimSpace(s), "_", "")
+	neg := strings.HasPrefix(num, "-")
+	if neg || strings.HasPrefix(num, "+") {
+		num = num[1:]
+	}
+	v, err := parse(s, num, decimals)
+	if err != nil {
+		return nil, err
+	}
+	if neg {
+		v.Neg(v)
+	}
+	return v, nil
+}
+
+// parse 解析不带符号的数字，s 是原始输入，用于错误信息
+func parse(s, num string, decimals uint8) (*big.Int, error) {
+	whole, frac := num, ""
+	if i := strings.IndexByte(num, '.'); i >= 0 {
+		whole, frac = num[:i], num[i+1:]
+	}
+	if whole == "" && frac == "" || !digits(whole) || !digits(frac) {
+		return nil, fmt.Errorf("%w %q", ErrSyntax, s)
+	}
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > int(decimals) {
+		return nil, fmt.Errorf("%w: %q has %d decimals, at most %d", ErrPrecision, s, len(frac), decimals)
+	}
+	frac += strings.Repeat("0", int(decimals)-len(frac))
+
+	v, ok := new(big.Int).SetString(whole+frac, 10)
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrSyntax, s)
+	}
+	return v, nil
+}
+
+func digits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseAmount parses an amount with an optional unit suffix, e.g.
+// "1.5 ether", "30gwei" or "12.34 MTK". Numbers without a suffix are in def.
+// Only def and the given units are accepted; names are matched case
+// insensitively.
+func ParseAmount(s string, def Unit, units ...Unit) (*big.Int, error) {
+	// 单位和数字之间可以有空格("12.34 USDC2")，也可以紧挨着("30gwei")，紧挨着时单位只能是字母
+	s = strings.TrimSpace(s)
+	num, name := s, ""
+	if i := strings.LastIndexByte(s, ' '); i >= 0 {
+		num, name = strings.TrimSpace(s[:i]), s[i+1:]
+	} else {
+		i := len(s)
+		for i > 0 && ('a' <= s[i-1] && s[i-1] <= 'z' || 'A' <= s[i-1] && s[i-1] <= 'Z') {
+			i--
+		}
+		num, name = s[:i], s[i:]
+	}
+	if name == "" {
+		return Parse(num, def.Decimals)
+	}
+	for _, u := range append([]Unit{def}, units...) {
+		if strings.EqualFold(u.Name, name) {
+			return Parse(num, u.Decimals)
+		}
+	}
+	return nil, fmt.Errorf("%w %q in %q", ErrUnit, name, s)
+}
+
+// ParseEther parses an ETH amount into wei. Bare numbers are wei, so
+// existing flags that took wei keep working.
+func ParseEther(s string) (*big.Int, error) {
+	return ParseAmount(s, Wei, EtherUnits...)
+}
+
+// Rounding selects how Format drops digits.
+type Rounding int
+
+const (
+	RoundHalfUp Rounding = iota // 四舍五入(远离零)
+	RoundDown                   // 直接截断(向零)
+)
+
+// Options control Format.
+type Options struct {
+	Precision int  // 最多保留的小数位数，负数或大于单位小数位数时保留全部，0 为只保留整数
+	Group     bool // 整数部分每三位加逗号
+	Rounding  Rounding
+	Fixed     bool // 保留末尾的 0，补足 Precision 位
+}
+
+// Exact is the default: every significant digit, nothing rounded.
+var Exact = Options{Precision: -1}
+
+// Format renders v, an amount in the smallest unit, as a decimal number of
+// a denomination with the given decimals.
+func Format(v *big.Int, decimals uint8, opts Options) string {
+	if v == nil {
+		v = new(big.Int)
+	}
+	abs := new(big.Int).Abs(v)
+	precision := opts.Precision
+	if precision < 0 || precision > int(decimals) {
+		precision = int(decimals)
+	}
+
+	// 先按需要的小数位数舍入，再拆成整数和小数部分
+	if drop := int(decimals) - precision; drop > 0 {
+		div := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(drop)), nil)
+		q, r := new(big.Int).QuoRem(abs, div, new(big.Int))
+		if opts.Rounding == RoundHalfUp && r.Lsh(r, 1).Cmp(div) >= 0 {
+			q.Add(q, big.NewInt(1))
+		}
+		abs = q
+	}
+	s := abs.String()
+	if len(s) <= precision {
+		s = strings.Repeat("0", precision-len(s)+1) + s
+	}
+	whole, frac := s[:len(s)-precision], s[len(s)-precision:]
+	if !opts.Fixed {
+		frac = strings.TrimRight(frac, "0")
+	}
+	if opts.Group {
+		whole = group(whole)
+	}
+
+	out := whole
+	if frac != "" {
+		out += "." + frac
+	}
+	if v.Sign() < 0 && strings.Trim(out, "0.,") != "" {
+		out = "-" + out
+	}
+	return out
+}
+
+func group(s string) string {
+	if len(s) <= 3 {
+		return s
+	}
+	var b strings.Builder
+	head := len(s) % 3
+	if head > 0 {
+		b.WriteString(s[:head])
+	}
+	for i := head; i < len(s); i += 3 {
+		if b.Len() > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(s[i : i+3])
+	}
+	return b.String()
+}
+
+// FormatUnit is Format followed by the unit name, e.g. "1.5 ETH".
+func FormatUnit(v *big.Int, u Unit, opts Options) string {
+	return Format(v, u.Decimals, opts) + " " + u.Name
+}
+
+// FormatEther renders wei as an exact ETH amount, e.g. "1.5 ETH".
+func FormatEther(wei *big.Int) string {
+	return FormatUnit(wei, ETH, Exact)
+}
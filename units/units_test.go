@@ -0,0 +1,141 @@
+package units
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in       string
+		decimals uint8
+		want     string
+		err      error
+	}{
+		{"1.5", 18, "1500000000000000000", nil},
+		{"0.000000000000000001", 18, "1", nil},
+		{".5", 6, "500000", nil},
+		{"5.", 6, "5000000", nil},
+		{"1_000_000", 0, "1000000", nil},
+		{" 42 ", 2, "4200", nil},
+		// 超出小数位数：末尾的 0 可以，其他数字报错
+		{"1.2300", 2, "123", nil},
+		{"1.234", 2, "", ErrPrecision},
+		{"0.0000000000000000001", 18, "", ErrPrecision},
+		{"0.5", 0, "", ErrPrecision},
+		// 格式错误
+		{"", 18, "", ErrSyntax},
+		{".", 18, "", ErrSyntax},
+		{"1.2.3", 18, "", ErrSyntax},
+		{"1e18", 18, "", ErrSyntax},
+		{"0x10", 18, "", ErrSyntax},
+		{"1,000", 18, "", ErrSyntax},
+		{"abc", 18, "", ErrSyntax},
+		// 不接受符号
+		{"-1", 18, "", ErrSyntax},
+		{"+1", 18, "", ErrSyntax},
+		{"-+1", 18, "", ErrSyntax},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in, tt.decimals)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Parse(%q, %d) = %v, %v, want %v", tt.in, tt.decimals, got, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got.String() != tt.want {
+			t.Errorf("Parse(%q, %d) = %v, %v, want %s", tt.in, tt.decimals, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseSigned(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		ok   bool
+	}{
+		{"-0.25", "-250", true},
+		{"+1", "1000", true},
+		{"1", "1000", true},
+		{"-+1", "", false},
+		{"--1", "", false},
+		{"-", "", false},
+	}
+	for _, tt := range tests {
+		got, err := ParseSigned(tt.in, 3)
+		if (err == nil) != tt.ok || tt.ok && got.String() != tt.want {
+			t.Errorf("ParseSigned(%q) = %v, %v, want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+		err  error
+	}{
+		{"100", "100", nil}, // 不带单位是 wei
+		{"1.5 ether", "1500000000000000000", nil},
+		{"30gwei", "30000000000", nil},
+		{"2 ETH", "2000000000000000000", nil},
+		{"0.5 wei", "", ErrPrecision},
+		{"1 usd", "", ErrUnit},
+		{"-1 ether", "", ErrSyntax},
+	}
+	for _, tt := range tests {
+		got, err := ParseEther(tt.in)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Errorf("ParseEther(%q) = %v, %v, want %v", tt.in, got, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got.String() != tt.want {
+			t.Errorf("ParseEther(%q) = %v, %v, want %s", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	n := func(s string) *big.Int {
+		v, _ := new(big.Int).SetString(s, 10)
+		return v
+	}
+	tests := []struct {
+		v        string
+		decimals uint8
+		opts     Options
+		want     string
+	}{
+		{"1500000000000000000", 18, Exact, "1.5"},
+		{"1", 18, Exact, "0.000000000000000001"},
+		{"0", 18, Exact, "0"},
+		{"-2500", 3, Exact, "-2.5"},
+		// 舍入
+		{"1235", 3, Options{Precision: 2}, "1.24"},
+		{"1234", 3, Options{Precision: 2}, "1.23"},
+		{"1235", 3, Options{Precision: 2, Rounding: RoundDown}, "1.23"},
+		{"1999", 3, Options{Precision: 2}, "2"},
+		{"1999", 3, Options{Precision: 2, Fixed: true}, "2.00"},
+		{"-1235", 3, Options{Precision: 2}, "-1.24"},
+		{"-4", 3, Options{Precision: 2}, "0"},
+		{"1500", 3, Options{Precision: 0}, "2"},
+		// 千位分组
+		{"1234567891", 3, Options{Precision: -1, Group: true}, "1,234,567.891"},
+		{"123456", 0, Options{Precision: -1, Group: true}, "123,456"},
+		{"999", 0, Options{Precision: -1, Group: true}, "999"},
+		{"-1000000", 0, Options{Precision: -1, Group: true}, "-1,000,000"},
+	}
+	for _, tt := range tests {
+		if got := Format(n(tt.v), tt.decimals, tt.opts); got != tt.want {
+			t.Errorf("Format(%s, %d, %+v) = %q, want %q", tt.v, tt.decimals, tt.opts, got, tt.want)
+		}
+	}
+	if got := FormatEther(n("1500000000000000000")); got != "1.5 ETH" {
+		t.Errorf("FormatEther = %q", got)
+	}
+}
@@ -0,0 +1,232 @@
+// Package vanity 并行搜索符合指定前缀、后缀或包含某段字符的地址。
+// 每个 goroutine 从一个随机私钥 k 开始，之后每次把公钥加上基点 G(对应私钥 k+1)，
+// 只做一次点加法而不是完整的标量乘法，速度快很多。
+package vanity
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Pattern describes the wanted address. Letters only have to match in case
+// when CaseSensitive is set, in which case the EIP-55 checksum casing is
+// compared. With Contract set the pattern applies to the address of the
+// contract the key deploys at nonce 0 instead of the key's own address.
+type Pattern struct {
+	Prefix        string
+	Suffix        string
+	Contains      string
+	CaseSensitive bool
+	Contract      bool
+}
+
+// Validate checks that the pattern only has hex characters and fits in an
+// address.
+func (p Pattern) Validate() error {
+	if p.Prefix == "" && p.Suffix == "" && p.Contains == "" {
+		return errors.New("empty pattern")
+	}
+	for _, s := range []string{p.Prefix, p.Suffix, p.Contains} {
+		for _, c := range s {
+			if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+				return fmt.Errorf("%q is not hex", s)
+			}
+		}
+	}
+	if len(p.Prefix)+len(p.Suffix) > 40 || len(p.Contains) > 40 {
+		return errors.New("pattern longer than an address")
+	}
+	return nil
+}
+
+// Match reports whether addr matches the pattern.
+func (p Pattern) Match(addr common.Address) bool {
+	// 先用小写比较，命中后再计算校验和大小写，绝大多数地址到不了这一步
+	hex := common.Bytes2Hex(addr[:])
+	if !p.match(hex, strings.ToLower) {
+		return false
+	}
+	if !p.CaseSensitive {
+		return true
+	}
+	return p.match(addr.Hex()[2:], func(s string) string { return s })
+}
+
+func (p Pattern) match(hex string, norm func(string) string) bool {
+	return strings.HasPrefix(hex, norm(p.Prefix)) &&
+		strings.HasSuffix(hex, norm(p.Suffix)) &&
+		strings.Contains(hex, norm(p.Contains))
+}
+
+// Difficulty is the expected number of keys to try before a match.
+func (p Pattern) Difficulty() float64 {
+	chars := func(s string) float64 {
+		d := math.Pow(16, float64(len(s)))
+		if p.CaseSensitive {
+			// 每个字母的大小写由校验和决定，大约一半概率
+			letters := 0
+			for _, c := range s {
+				if unicode.IsLetter(c) {
+					letters++
+				}
+			}
+			d *= math.Pow(2, float64(letters))
+		}
+		return d
+	}
+	d := chars(p.Prefix) * chars(p.Suffix)
+	if p.Contains != "" {
+		// 任意位置都可以出现，粗略按可能的起始位置数折算
+		d *= chars(p.Contains) / float64(41-len(p.Contains))
+	}
+	return math.Max(d, 1)
+}
+
+// Result is a matching key.
+type Result struct {
+	Key      *ecdsa.PrivateKey
+	Address  common.Address // 私钥对应的账户地址
+	Contract common.Address // 该账户 nonce 为 0 时部署的合约地址
+}
+
+// Stats reports search progress.
+type Stats struct {
+	Tried   uint64
+	Elapsed time.Duration
+}
+
+// Rate returns keys per second.
+func (s Stats) Rate() float64 {
+	if s.Elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Tried) / s.Elapsed.Seconds()
+}
+
+// Search tries keys on workers goroutines until one matches p or ctx is
+// canceled. progress, if not nil, is called every interval.
+func Search(ctx context.Context, p Pattern, workers int, interval time.Duration, progress func(Stats)) (*Result, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		tried  uint64
+		once   sync.Once
+		result *Result
+		wg     sync.WaitGroup
+		errc   = make(chan error, workers)
+	)
+	start := time.Now()
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r, err := search(ctx, p, &tried)
+			if err != nil {
+				errc <- err
+				// 让其他 worker 也停下来，否则 Search 会一直等下去
+				cancel()
+				return
+			}
+			if r != nil {
+				once.Do(func() { result = r })
+				cancel()
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	if progress != nil && interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+	loop:
+		for {
+			select {
+			case <-ticker.C:
+				progress(Stats{Tried: atomic.LoadUint64(&tried), Elapsed: time.Since(start)})
+			case <-done:
+				break loop
+			}
+		}
+	}
+	<-done
+
+	select {
+	case err := <-errc:
+		return nil, err
+	default:
+	}
+	if result == nil {
+		return nil, ctx.Err()
+	}
+	return result, nil
+}
+
+// search 是单个 worker 的循环，每 1024 次检查一次是否需要停止
+func search(ctx context.Context, p Pattern, tried *uint64) (*Result, error) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	curve := crypto.S256()
+	params := curve.Params()
+	k := new(big.Int).Set(key.D)
+	x, y := new(big.Int).Set(key.X), new(big.Int).Set(key.Y)
+
+	buf := make([]byte, 64)
+	for {
+		for i := 0; i < 1024; i++ {
+			x.FillBytes(buf[:32])
+			y.FillBytes(buf[32:])
+			addr := common.BytesToAddress(crypto.Keccak256(buf)[12:])
+			target := addr
+			if p.Contract {
+				target = crypto.CreateAddress(addr, 0)
+			}
+			if p.Match(target) {
+				atomic.AddUint64(tried, uint64(i+1))
+				priv, err := crypto.ToECDSA(common.LeftPadBytes(k.Bytes(), 32))
+				if err != nil {
+					return nil, err
+				}
+				// 重新由私钥推导一次，防止点加法的实现出错导致私钥和地址对不上
+				if crypto.PubkeyToAddress(priv.PublicKey) != addr {
+					return nil, errors.New("vanity: derived key does not match address")
+				}
+				return &Result{Key: priv, Address: addr, Contract: crypto.CreateAddress(addr, 0)}, nil
+			}
+			x, y = curve.Add(x, y, params.Gx, params.Gy)
+			k.Add(k, common.Big1)
+			if k.Cmp(params.N) >= 0 {
+				// 极小概率绕回，重新随机一个起点
+				return search(ctx, p, tried)
+			}
+		}
+		atomic.AddUint64(tried, 1024)
+		if ctx.Err() != nil {
+			return nil, nil
+		}
+	}
+}